@@ -1,8 +1,14 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	ednet "github.com/KirtusLeyba/edamame/core/networks"
-	// "strconv"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -11,9 +17,18 @@ type EdamameOptions struct{
 	NodeFilePath, EdgeFilePath, OutputFilePath string
 	MaxWorkers, MaxIters int
 	Repulsion float64
+	Layout, LayoutParams string
+	Directed bool
+	Tolerance, EnergyTolerance float64
+	Patience int
+	Seed int64
+	Communities string
+	PackComponents bool
+	Stats string
+	RemoveOverlaps bool
 }
 
-func Execute(defaultWidth, defaultHeight int32) {
+func Execute(defaultWidth, defaultHeight int32, seed int64) {
 
 	initWindow(defaultWidth, defaultHeight)
 	defer rl.CloseWindow()
@@ -21,20 +36,21 @@ func Execute(defaultWidth, defaultHeight int32) {
 	//set up layer tree
 	//with a ui layer as root
 	var ui UILayer
+	ui.seed = resolveSeed(seed)
 	ui.SetTransform(Vec2Df32{0.0, 0.0}, Vec2Df32{1.0, 1.0})
 	root := NewRootLayerTreeNode(&ui)
 
 	//add the netviz layer
 	var netLayer  NetworkLayer
 	netLayer.SetTransform(Vec2Df32{0.1, 0.1}, Vec2Df32{0.8, 0.8})
-	netLayer.Equilibrium = 8.0
-	netLayer.Repulsion = 80.0
-	netLayer.SpringConstant = 0.1
-	netLayer.StepSize = 0.1
-	netLayer.Friction = 0.125
 	netLayer.MaxIters = 100
-	netLayer.MaxWorkers = 10
+	netLayer.Monitor = ednet.NewConvergenceMonitor(0.01, 10)
 	netLayer.Net = ednet.NewSpatialNet()
+	err := netLayer.SetLayout("spring")
+	if err != nil {
+		log.Fatal(err)
+	}
+	applyLayoutParameters(netLayer.Layout, map[string]float32{"maxWorkers": 10})
 	root.AddChild(&netLayer)
 
 	mainLoop(root)
@@ -43,16 +59,31 @@ func Execute(defaultWidth, defaultHeight int32) {
 
 func ExecuteHeadless(opt *EdamameOptions){
 	var headless HeadlessLayer
+	opt.Seed = resolveSeed(opt.Seed)
 	headless.opt = opt
-	headless.Equilibrium = 8.0
-	headless.Repulsion = float32(opt.Repulsion)
-	headless.SpringConstant = 0.1
-	headless.StepSize = 0.1
-	headless.Friction = 0.125
 	headless.MaxIters = opt.MaxIters
-	headless.MaxWorkers = uint(opt.MaxWorkers)
 	headless.Net = ednet.NewSpatialNet()
 
+	layout, err := newLayout(opt.Layout, opt.PackComponents)
+	if err != nil {
+		log.Fatal(err)
+	}
+	applyLayoutParameters(layout, map[string]float32{"repulsion": float32(opt.Repulsion),
+		"maxWorkers": float32(opt.MaxWorkers)})
+	userParams, err := parseLayoutParameters(opt.LayoutParams)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, value := range userParams {
+		err = layout.SetParameter(name, value)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	headless.Layout = layout
+	headless.Monitor = ednet.NewConvergenceMonitor(float32(opt.Tolerance), opt.Patience)
+	headless.Monitor.EnergyTolerance = opt.EnergyTolerance
+
 	root := NewRootLayerTreeNode(&headless)
 	mainLoopHeadless(root)
 }
@@ -87,3 +118,266 @@ func mainLoopHeadless(root *LayerTreeNode){
 		root.RenderTree()
 	}
 }
+
+//exported images draw radii at twice the scale of distances,
+//so overlaps are removed with radii doubled to match
+const exportRadiusScale = 2.0
+
+/**
+ * Push apart nodes that would overlap in an exported image.
+ * Returns the number of overlapping pairs left.
+ */
+func removeOverlaps(net *ednet.SpatialNet) int {
+	return net.RemoveOverlaps(exportRadiusScale, 1.0, 500)
+}
+
+//networks up to this many nodes get an exact diameter in their stats
+const exactDiameterNodes = 5000
+
+/**
+ * Print a summary of the network to stdout, formatted as
+ * a table or as json.
+ */
+func printStats(net *ednet.SpatialNet, format string, maxWorkers uint) error {
+	stats := net.Stats(len(net.NodeSlice) <= exactDiameterNodes, maxWorkers)
+	switch format {
+	case "table":
+		fmt.Print(stats)
+	case "json":
+		out, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	default:
+		return errors.New("unknown stats format " + format)
+	}
+	return nil
+}
+
+/**
+ * Detect communities with the named method, one of louvain, leiden
+ * or labelpropagation, and colour nodes by community.
+ */
+func detectCommunities(net *ednet.SpatialNet, method string, seed int64) (ednet.Communities, error) {
+	opt := ednet.NewCommunityOptions()
+	opt.Seed = seed
+	var communities ednet.Communities
+	switch method {
+	case "louvain":
+		communities = net.Louvain(opt)
+	case "leiden":
+		communities = net.Leiden(opt)
+	case "labelpropagation":
+		communities = net.LabelPropagation(opt)
+	default:
+		return communities, errors.New("unknown community detection method " + method)
+	}
+	err := net.StoreCommunities("community", communities)
+	return communities, err
+}
+
+/**
+ * Pick a random seed when none was given, and log the seed
+ * so the run can be reproduced with -seed.
+ */
+func resolveSeed(seed int64) int64 {
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	log.Printf("Using seed %v\n", seed)
+	return seed
+}
+
+/**
+ * Create the layout registered under name. With pack set, every
+ * connected component is laid out on its own and packed together.
+ */
+func newLayout(name string, pack bool) (ednet.Layout, error) {
+	if pack {
+		return ednet.NewPackedLayout(name)
+	}
+	return ednet.NewLayout(name)
+}
+
+/**
+ * Set the parameters a layout knows about, skipping the rest.
+ * Used to carry settings over when switching between layouts.
+ */
+func applyLayoutParameters(layout ednet.Layout, params map[string]float32) {
+	known := layout.Parameters()
+	for name, value := range params {
+		if _, exists := known[name]; exists {
+			layout.SetParameter(name, value)
+		}
+	}
+}
+
+/**
+ * Parse layout parameters of the form "name=value,name=value".
+ */
+func parseLayoutParameters(s string) (map[string]float32, error) {
+	params := make(map[string]float32)
+	if strings.TrimSpace(s) == "" {
+		return params, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, valueStr, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, errors.New("bad layout parameter " + pair + ", expected name=value")
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 32)
+		if err != nil {
+			return nil, err
+		}
+		params[strings.TrimSpace(name)] = float32(value)
+	}
+	return params, nil
+}
+
+/**
+ * Place and pin nodes from the optional x, y and pinned columns of a
+ * node csv. Nodes with an x or y value start there instead of their
+ * random position, and nodes marked pinned stay put during layouts.
+ * Returns the columns it read, which are not attributes.
+ */
+func applyNodePositions(net *ednet.SpatialNet, records [][]string) (map[int]bool, error) {
+	used := make(map[int]bool)
+	if len(records) == 0 {
+		return used, nil
+	}
+	columns := map[string]int{"x": -1, "y": -1, "pinned": -1}
+	for col, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, exists := columns[name]; exists && col >= 2 {
+			columns[name] = col
+			used[col] = true
+		}
+	}
+	for _, record := range records[1:] {
+		node := &net.NodeSlice[net.NodeIndeces[record[0]]]
+		if col := columns["x"]; col >= 0 && record[col] != "" {
+			x, err := strconv.ParseFloat(record[col], 32)
+			if err != nil {
+				return nil, err
+			}
+			node.X = float32(x)
+		}
+		if col := columns["y"]; col >= 0 && record[col] != "" {
+			y, err := strconv.ParseFloat(record[col], 32)
+			if err != nil {
+				return nil, err
+			}
+			node.Y = float32(y)
+		}
+		if col := columns["pinned"]; col >= 0 && record[col] != "" {
+			pinned, err := strconv.ParseBool(record[col])
+			if err != nil {
+				return nil, err
+			}
+			node.Pinned = pinned
+		}
+	}
+	return used, nil
+}
+
+/**
+ * Store the columns of a node or edge csv from column first onwards
+ * as attributes, using the header for attribute names. Columns named
+ * r, g, b and optionally a are combined into the "color" attribute,
+ * every other column gets the narrowest type all its values parse as.
+ * Columns in skip are left out.
+ */
+func storeAttributes[K comparable](table *ednet.AttributeTable[K],
+	records [][]string,
+	first int,
+	skip map[int]bool,
+	key func(record []string) K) error {
+
+	if len(records) == 0 {
+		return nil
+	}
+	header := records[0]
+	rows := records[1:]
+
+	colorColumns := make(map[string]int)
+	for col := first; col < len(header); col++ {
+		switch strings.ToLower(strings.TrimSpace(header[col])) {
+		case "r", "g", "b", "a":
+			colorColumns[strings.ToLower(strings.TrimSpace(header[col]))] = col
+		}
+	}
+	_, hasR := colorColumns["r"]
+	_, hasG := colorColumns["g"]
+	_, hasB := colorColumns["b"]
+	hasColor := hasR && hasG && hasB
+	if hasColor {
+		err := table.Define("color", ednet.AttributeColor)
+		if err != nil {
+			return err
+		}
+		for _, record := range rows {
+			channels := []string{record[colorColumns["r"]],
+				record[colorColumns["g"]],
+				record[colorColumns["b"]]}
+			if col, exists := colorColumns["a"]; exists {
+				channels = append(channels, record[col])
+			}
+			color, err := ednet.ParseColor(channels)
+			if err != nil {
+				return err
+			}
+			table.SetColor(key(record), "color", color)
+		}
+	}
+
+	for col := first; col < len(header); col++ {
+		if skip[col] {
+			continue
+		}
+		name := strings.TrimSpace(header[col])
+		if _, isColor := colorColumns[strings.ToLower(name)]; isColor && hasColor {
+			continue
+		}
+		values := make([]string, len(rows))
+		for i, record := range rows {
+			values[i] = record[col]
+		}
+		err := table.Define(name, ednet.InferAttributeType(values))
+		if err != nil {
+			return err
+		}
+		for i, record := range rows {
+			if values[i] == "" {
+				continue
+			}
+			err = table.SetParsed(key(record), name, values[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/**
+ * Color of a node from its "color" attribute, or fallback if it has none.
+ */
+func attributeNodeColor(net *ednet.SpatialNet, name string, fallback rl.Color) rl.Color {
+	c, exists := net.NodeAttributes.Color(name, "color")
+	if !exists {
+		return fallback
+	}
+	return rl.NewColor(c.R, c.G, c.B, c.A)
+}
+
+/**
+ * Color of an edge from its "color" attribute, or fallback if it has none.
+ */
+func attributeEdgeColor(net *ednet.SpatialNet, nameA, nameB string, fallback rl.Color) rl.Color {
+	c, exists := net.EdgeAttributes.Color(net.EdgeKey(nameA, nameB), "color")
+	if !exists {
+		return fallback
+	}
+	return rl.NewColor(c.R, c.G, c.B, c.A)
+}
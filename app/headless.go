@@ -1,14 +1,18 @@
 package app
 
 import (
+	"context"
 	"encoding/csv"
 	ednet "github.com/KirtusLeyba/edamame/core/networks"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"log"
-	"math/rand"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 // type Layer interface {
@@ -23,14 +27,15 @@ import (
 // }
 
 type HeadlessLayer struct {
-	ltNode                                                     *LayerTreeNode
-	opt                                                        *EdamameOptions
-	Net                                                        *ednet.SpatialNet
-	SpringConstant, StepSize, Equilibrium, Repulsion, Friction float32
-	currentIteration, lastIteration                            int
-	MaxIters                                                   int
-	MaxWorkers                                                 uint
-	finished                                                   bool
+	ltNode   *LayerTreeNode
+	opt      *EdamameOptions
+	Net      *ednet.SpatialNet
+	Layout   ednet.Layout
+	Monitor  *ednet.ConvergenceMonitor
+	MaxIters int
+
+	//set by the layout go routine once it is done
+	finished atomic.Bool
 }
 
 func logHeadless(msg string) {
@@ -43,28 +48,67 @@ func (hl *HeadlessLayer) OnCreate() {
 		", and edge data from: " +
 		hl.opt.EdgeFilePath)
 	hl.loadNodeData(hl.opt.NodeFilePath)
-	hl.loadEdgeData(hl.opt.EdgeFilePath)
+	hl.loadEdgeData(hl.opt.EdgeFilePath, hl.opt.Directed)
+
+	if hl.opt.Communities != "" {
+		communities, err := detectCommunities(hl.Net, hl.opt.Communities, hl.opt.Seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		logHeadless("Found " + strconv.Itoa(communities.Count) + " communities with modularity " +
+			strconv.FormatFloat(communities.Modularity, 'f', 4, 64))
+	}
 
-	hl.currentIteration = 0
-	hl.lastIteration = 0
+	if hl.opt.Stats != "" {
+		err := printStats(hl.Net, hl.opt.Stats, uint(hl.opt.MaxWorkers))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	logHeadless("Computing layout")
-	hl.finished = false
+	logHeadless("Computing layout with " + hl.opt.Layout + ", press Ctrl-C to stop early")
+	hl.finished.Store(false)
+
+	//Ctrl-C cancels the run, the layout so far is still written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	runner := ednet.LayoutRunner{MaxIters: hl.MaxIters, Monitor: hl.Monitor, Observer: hl.logProgress}
 	go func() {
-		for range hl.MaxIters {
-			hl.Net.SpringUpdateParallel(
-				hl.SpringConstant,
-				hl.StepSize,
-				hl.Equilibrium,
-				hl.Repulsion,
-				hl.Friction,
-				hl.MaxWorkers)
-			hl.currentIteration++
+		result, err := runner.Run(ctx, hl.Layout, hl.Net)
+		stop()
+		if err != nil {
+			log.Fatal(err)
+		}
+		switch {
+		case result.Converged:
+			logHeadless("Layout converged after " + strconv.Itoa(result.Iterations) + " iterations")
+		case result.Cancelled:
+			logHeadless("Layout stopped after " + strconv.Itoa(result.Iterations) + " iterations")
+		}
+		logHeadless("Layout took " + result.Elapsed.Round(time.Millisecond).String())
+		stress, isType := hl.Layout.(*ednet.StressLayout)
+		if isType {
+			logHeadless("Final stress: " + strconv.FormatFloat(stress.FinalStress(), 'f', 4, 64))
 		}
-		hl.finished = true
+		hl.finished.Store(true)
 	}()
 
 }
+
+/**
+ * Log every step of the layout, and the overall progress every 50 steps.
+ */
+func (hl *HeadlessLayer) logProgress(progress ednet.LayoutProgress) {
+	logHeadless("Iteration " + strconv.Itoa(progress.Iteration) +
+		": energy " + strconv.FormatFloat(progress.Energy, 'g', 6, 64) +
+		", max displacement " + strconv.FormatFloat(float64(progress.MaxDisplacement), 'g', 6, 32) +
+		", elapsed " + progress.Elapsed.Round(time.Millisecond).String())
+	done := progress.Iteration + 1
+	if done%50 == 0 {
+		fraction := float64(done) / float64(hl.MaxIters)
+		logHeadless("Progress: " + strconv.FormatFloat(fraction, 'f', 4, 64))
+	}
+}
+
 func (hl *HeadlessLayer) OnRemove() {
 	//TODO: Make these options the user can select
 	var imgSize uint = 8192
@@ -72,8 +116,13 @@ func (hl *HeadlessLayer) OnRemove() {
 	var spaceScale float32 = 2.0
 	var edgeScale float32 = 4.0
 
+	if hl.opt.RemoveOverlaps {
+		left := removeOverlaps(hl.Net)
+		logHeadless("Removed overlaps, " + strconv.Itoa(left) + " overlapping pairs left")
+	}
+
 	img := rl.GenImageColor(int(imgSize), int(imgSize), rl.White)
-	hl.DrawEdgesImage(img, imgSize, imgSize, edgeScale, spaceScale)
+	hl.DrawEdgesImage(img, imgSize, imgSize, edgeScale, nodeScale, spaceScale)
 	hl.DrawNodesImage(img, imgSize, imgSize, nodeScale, spaceScale)
 	rl.ExportImage(*img, hl.opt.OutputFilePath)
 	logHeadless("go routine finished layout iterations")
@@ -106,15 +155,7 @@ func (hl *HeadlessLayer) OnRemove() {
 
 func (hl *HeadlessLayer) OnEvent() {}
 func (hl *HeadlessLayer) OnUpdate() {
-
-	if hl.currentIteration != hl.lastIteration {
-		hl.lastIteration = hl.currentIteration
-		if hl.lastIteration%50 == 0 {
-			progress := float64(hl.lastIteration) / float64(hl.MaxIters)
-			logHeadless("Progress: " + strconv.FormatFloat(progress, 'f', 4, 64))
-		}
-	}
-	if hl.finished {
+	if hl.finished.Load() {
 		hl.ltNode.Remove()
 	}
 }
@@ -138,12 +179,13 @@ func (hl *HeadlessLayer) loadNodeData(fname string) {
 		log.Fatal(err)
 	}
 	hl.Net = ednet.NewSpatialNet()
+	hl.Net.Seed(hl.opt.Seed)
 	for lineIDX, record := range records {
 		//skip the header
 		if lineIDX == 0 {
 			continue
 		}
-		if len(record) != 6 {
+		if len(record) < 2 {
 			log.Fatal("Bad node data file!")
 		}
 		name := record[0]
@@ -151,32 +193,26 @@ func (hl *HeadlessLayer) loadNodeData(fname string) {
 		if err != nil {
 			log.Fatal(err)
 		}
-		//TODO: Store color data
-		// r, err := strconv.ParseUint(record[2], 10, 8)
-		// if err != nil {
-		// 	log.Fatal(err)
-		// }
-		// g, err := strconv.ParseUint(record[3], 10, 8)
-		// if err != nil {
-		// 	log.Fatal(err)
-		// }
-		// b, err := strconv.ParseUint(record[4], 10, 8)
-		// if err != nil {
-		// 	log.Fatal(err)
-		// }
-		// a, err := strconv.ParseUint(record[5], 10, 8)
-		// if err != nil {
-		// 	log.Fatal(err)
-		// }
 		hl.Net.AddNode(name)
 		var node *ednet.SpatialNetNode = &hl.Net.NodeSlice[len(hl.Net.NodeSlice)-1]
-		node.X = (100.0 * rand.Float32()) - 50.0
-		node.Y = (100.0 * rand.Float32()) - 50.0
+		node.X = (100.0 * hl.Net.Rng.Float32()) - 50.0
+		node.Y = (100.0 * hl.Net.Rng.Float32()) - 50.0
 		node.Radius = float32(radius)
 	}
+	positionColumns, err := applyNodePositions(hl.Net, records)
+	if err != nil {
+		log.Fatal(err)
+	}
+	//extra columns, including r,g,b,a, become node attributes
+	err = storeAttributes(hl.Net.NodeAttributes, records, 2, positionColumns, func(record []string) string {
+		return record[0]
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-func (hl *HeadlessLayer) loadEdgeData(fname string) {
+func (hl *HeadlessLayer) loadEdgeData(fname string, directed bool) {
 	content, err := os.ReadFile(fname)
 	if err != nil {
 		log.Fatal(err)
@@ -188,30 +224,32 @@ func (hl *HeadlessLayer) loadEdgeData(fname string) {
 	}
 
 	//Reset edge data in the SpatialNet
-	hl.Net.Adjacencies = make(ednet.EdgeSet)
-	for _, node := range hl.Net.NodeSlice {
-		hl.Net.Adjacencies[node.Name] = make(map[string]struct{})
-	}
+	hl.Net.ResetEdges(directed)
 	for lineIDX, record := range records {
 		//skip the header
 		if lineIDX == 0 {
 			continue
 		}
-		if len(record) != 3 {
+		if len(record) < 3 {
 			log.Fatal("Bad edge data file!")
 		}
 		nameA := record[0]
 		nameB := record[1]
-		//TODO: Store edge width
-		// width, err := strconv.ParseFloat(record[2], 32)
-		// if err != nil {
-		// 	log.Fatal(err)
-		// }
-		hl.Net.AddEdge(nameA, nameB)
+		width, err := strconv.ParseFloat(record[2], 32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hl.Net.AddWeightedEdge(nameA, nameB, float32(width))
+	}
+	err = storeAttributes(hl.Net.EdgeAttributes, records, 3, nil, func(record []string) ednet.EdgeKey {
+		return hl.Net.EdgeKey(record[0], record[1])
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 }
 
-func (hl *HeadlessLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeScale, spaceScale float32) {
+func (hl *HeadlessLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeScale, nodeScale, spaceScale float32) {
 	frame := rl.Rectangle{0.0, 0.0, float32(width), float32(height)}
 	cameraCenter := Vec2Df32{frame.X + frame.Width/2, frame.Y + frame.Height/2}
 	cx, cy := hl.Net.GetCOM()
@@ -220,6 +258,7 @@ func (hl *HeadlessLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeS
 		for targetNodeName, _ := range targetNodeSet {
 			nodeA := hl.Net.NodeSlice[hl.Net.NodeIndeces[sourceNodeName]]
 			nodeB := hl.Net.NodeSlice[hl.Net.NodeIndeces[targetNodeName]]
+			edgeColor := attributeEdgeColor(hl.Net, sourceNodeName, targetNodeName, rl.Black)
 			posRealA := Vec2Df32{nodeA.X, nodeA.Y}
 			posAdjustedA := Vec2Df32{posRealA.X - com.X,
 				posRealA.Y - com.Y}
@@ -238,12 +277,18 @@ func (hl *HeadlessLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeS
 			posAdjustedB.X = cameraCenter.X + posAdjustedB.X
 			posAdjustedB.Y = cameraCenter.Y + posAdjustedB.Y
 
-			edgeWidth := 10.0 //TODO: Make this an option
+			weight, _ := hl.Net.EdgeWeight(sourceNodeName, targetNodeName)
+			edgeWidth := max(weight*edgeScale, 1.0)
 
 			rl.ImageDrawLineEx(img,
 				rl.Vector2{X: posAdjustedA.X, Y: posAdjustedA.Y},
 				rl.Vector2{X: posAdjustedB.X, Y: posAdjustedB.Y},
-				int32(edgeWidth), rl.Black)
+				int32(edgeWidth), edgeColor)
+			if hl.Net.Directed {
+				v1, v2, v3 := arrowHead(posAdjustedA, posAdjustedB,
+					edgeWidth*3.0, nodeB.Radius*nodeScale)
+				rl.ImageDrawTriangle(img, v1, v2, v3, edgeColor)
+			}
 		}
 	}
 
@@ -263,7 +308,7 @@ func (hl *HeadlessLayer) DrawNodesImage(img *rl.Image, width, height uint, nodeS
 		posAdjusted.X = cameraCenter.X + posAdjusted.X
 		posAdjusted.Y = cameraCenter.Y + posAdjusted.Y
 		radius := n.Radius * nodeScale
-		nodeColor := rl.NewColor(0, 0, 255, 255)
+		nodeColor := attributeNodeColor(hl.Net, n.Name, rl.NewColor(0, 0, 255, 255))
 		rl.ImageDrawCircle(img, int32(posAdjusted.X), int32(posAdjusted.Y), int32(radius), nodeColor)
 		// rl.ImageDrawText(img, int32(posAdjusted.X), int32(posAdjusted.Y), n.Name, 8, rl.White)
 	}
@@ -1,21 +1,40 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"math"
 	ednet "github.com/KirtusLeyba/edamame/core/networks"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"log"
 )
 
 type NetworkLayer struct {
-	origin                                                     Vec2Df32
-	size                                                       Vec2Df32
-	ltNode                                                     *LayerTreeNode
-	Net                                                        *ednet.SpatialNet
-	SpringConstant, StepSize, Equilibrium, Repulsion, Friction float32
-	NodeTexture                                                rl.RenderTexture2D
-	StartLayout                                                bool
-	RunningLayout                                              bool
-	MaxIters, MaxWorkers                                       uint
+	origin         Vec2Df32
+	size           Vec2Df32
+	ltNode         *LayerTreeNode
+	Net            *ednet.SpatialNet
+	Layout         ednet.Layout
+	LayoutName     string
+	NodeTexture    rl.RenderTexture2D
+	StartLayout    bool
+	MaxIters       uint
+	Monitor        *ednet.ConvergenceMonitor
+	PackComponents bool
+
+	//nodes picked with left clicks, and the shortest path between them
+	PathSource string
+	PathTarget string
+	PathLength float32
+	pathNodes  map[string]bool
+	pathEdges  map[ednet.EdgeKey]bool
+
+	//cancels the running layout, layoutDone is closed once it stops
+	cancelLayout context.CancelFunc
+	layoutDone   chan struct{}
+
+	//nodes as last published, safe to draw while the layout runs
+	positions []ednet.SpatialNetNode
 }
 
 func (nl *NetworkLayer) OnCreate() {
@@ -34,31 +53,234 @@ func (nl *NetworkLayer) OnRemove() {
 func (nl *NetworkLayer) OnEvent() {}
 
 func (nl *NetworkLayer) OnUpdate() {
+	nl.refreshPositions()
 	if nl.StartLayout {
-		if !nl.RunningLayout {
-			nl.RunningLayout = true
-			go func() {
-				for range nl.MaxIters {
-					nl.Net.SpringUpdateParallel(
-						nl.SpringConstant,
-						nl.StepSize,
-						nl.Equilibrium,
-						nl.Repulsion,
-						nl.Friction,
-						nl.MaxWorkers)
-					if !nl.RunningLayout {
-						break
-					}
-				}
-				nl.RunningLayout = false
-			}()
+		if !nl.Running() {
+			nl.runLayout()
 		} else {
-			nl.RunningLayout = false
+			nl.cancelLayout()
 		}
 		nl.StartLayout = false
 	}
+
+	//right click a node to prune it from the network
+	if rl.IsMouseButtonReleased(rl.MouseButtonRight) && !nl.Running() {
+		name, found := nl.nodeAt(rl.GetMousePosition())
+		if found {
+			err := nl.Net.RemoveNode(name)
+			if err != nil {
+				log.Print(err)
+			}
+			if name == nl.PathSource || name == nl.PathTarget {
+				nl.ClearPath()
+			} else if nl.PathTarget != "" {
+				nl.updatePath()
+			}
+		}
+	}
+
+	//left click a source and then a target node to highlight
+	//the shortest path between them, click the source again to clear
+	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+		name, found := nl.nodeAt(rl.GetMousePosition())
+		if found {
+			switch {
+			case name == nl.PathSource:
+				nl.ClearPath()
+			case nl.PathSource == "" || nl.PathTarget != "":
+				nl.ClearPath()
+				nl.PathSource = name
+				nl.pathNodes = map[string]bool{name: true}
+			default:
+				nl.PathTarget = name
+				nl.updatePath()
+			}
+		}
+	}
+}
+
+/**
+ * Run the layout in its own go routine until it converges, reaches
+ * MaxIters or is cancelled, publishing positions after every step.
+ */
+func (nl *NetworkLayer) runLayout() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	nl.cancelLayout = cancel
+	nl.layoutDone = done
+
+	//the go routine keeps its own references, and the shared
+	//index view is built before it starts
+	net, layout := nl.Net, nl.Layout
+	net.CSR()
+	runner := ednet.LayoutRunner{MaxIters: int(nl.MaxIters), Monitor: nl.Monitor}
+	go func() {
+		defer close(done)
+		_, err := runner.Run(ctx, layout, net)
+		if err != nil {
+			log.Print(err)
+		}
+	}()
+}
+
+/**
+ * Whether a layout is running.
+ */
+func (nl *NetworkLayer) Running() bool {
+	if nl.layoutDone == nil {
+		return false
+	}
+	select {
+	case <-nl.layoutDone:
+		return false
+	default:
+		return true
+	}
+}
+
+/**
+ * Cancel the running layout, if any, and wait for it to stop.
+ */
+func (nl *NetworkLayer) StopLayout() {
+	if !nl.Running() {
+		return
+	}
+	nl.cancelLayout()
+	<-nl.layoutDone
 }
+
+/**
+ * Push apart overlapping nodes, keeping the shape of the layout.
+ */
+func (nl *NetworkLayer) RemoveOverlaps() error {
+	if nl.Running() {
+		return errors.New("cannot remove overlaps while the layout is running")
+	}
+	left := removeOverlaps(nl.Net)
+	if left > 0 {
+		log.Printf("%v overlapping pairs of nodes are left\n", left)
+	}
+	return nil
+}
+
+/**
+ * Copy the last published positions for drawing. While no layout
+ * runs this thread owns the nodes, so it publishes them first.
+ */
+func (nl *NetworkLayer) refreshPositions() {
+	if nl.Net == nil {
+		return
+	}
+	if !nl.Running() {
+		nl.Net.PublishPositions()
+	}
+	nl.positions = nl.Net.ReadPositions(nl.positions)
+}
+
+/**
+ * Find the shortest path from PathSource to PathTarget. If the network
+ * has edge weights, paths follow the strongest ties.
+ */
+func (nl *NetworkLayer) updatePath() {
+	nl.pathNodes = map[string]bool{nl.PathSource: true, nl.PathTarget: true}
+	nl.pathEdges = make(map[ednet.EdgeKey]bool)
+	nl.PathLength = 0
+	path, length, err := nl.Net.ShortestPath(nl.PathSource, nl.PathTarget, nl.Net.HasEdgeWeights())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	nl.PathLength = length
+	for i, name := range path {
+		nl.pathNodes[name] = true
+		if i > 0 {
+			nl.pathEdges[nl.Net.EdgeKey(path[i-1], name)] = true
+		}
+	}
+}
+
+/**
+ * Stop highlighting a path.
+ */
+func (nl *NetworkLayer) ClearPath() {
+	nl.PathSource = ""
+	nl.PathTarget = ""
+	nl.PathLength = 0
+	nl.pathNodes = nil
+	nl.pathEdges = nil
+}
+
+/**
+ * Find the node drawn under a point on the screen.
+ */
+func (nl *NetworkLayer) nodeAt(pos rl.Vector2) (string, bool) {
+	if len(nl.positions) == 0 {
+		return "", false
+	}
+	frame := nl.ltNode.GetFrame()
+	cameraCenter := Vec2Df32{frame.X + frame.Width/2, frame.Y + frame.Height/2}
+	cx, cy := ednet.CenterOfMass(nl.positions)
+	//TODO: don't hardcode size of circle texture
+	for _, n := range nl.positions {
+		x := cameraCenter.X + n.X - cx + 16
+		y := cameraCenter.Y + n.Y - cy + 16
+		if math.Hypot(float64(pos.X-x), float64(pos.Y-y)) <= 8.0 {
+			return n.Name, true
+		}
+	}
+	return "", false
+}
+/**
+ * Switch to the layout registered under name, carrying over
+ * any parameters the old and new layouts have in common.
+ * Components are laid out separately if PackComponents is set.
+ */
+func (nl *NetworkLayer) SetLayout(name string) error {
+	if nl.Running() {
+		return errors.New("cannot change layout while it is running")
+	}
+	layout, err := newLayout(name, nl.PackComponents)
+	if err != nil {
+		return err
+	}
+	if nl.Layout != nil {
+		applyLayoutParameters(layout, nl.Layout.Parameters())
+	}
+	nl.Layout = layout
+	nl.LayoutName = name
+	return nil
+}
+
+/**
+ * Compute the corners of an arrowhead pointing from a to b. The tip is
+ * pulled back from b by backoff so it isn't hidden under the target node.
+ * Corners are returned in the winding order raylib expects.
+ */
+func arrowHead(a, b Vec2Df32, size, backoff float32) (rl.Vector2, rl.Vector2, rl.Vector2) {
+	dx := b.X - a.X
+	dy := b.Y - a.Y
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	if length == 0 {
+		return rl.Vector2{X: b.X, Y: b.Y}, rl.Vector2{X: b.X, Y: b.Y}, rl.Vector2{X: b.X, Y: b.Y}
+	}
+	dx /= length
+	dy /= length
+	tip := rl.Vector2{X: b.X - dx*backoff, Y: b.Y - dy*backoff}
+	baseX := tip.X - dx*size
+	baseY := tip.Y - dy*size
+	left := rl.Vector2{X: baseX - dy*size/2, Y: baseY + dx*size/2}
+	right := rl.Vector2{X: baseX + dy*size/2, Y: baseY - dx*size/2}
+
+	//raylib culls triangles that are not counter-clockwise on screen
+	cross := (left.X-tip.X)*(right.Y-tip.Y) - (left.Y-tip.Y)*(right.X-tip.X)
+	if cross > 0 {
+		return tip, right, left
+	}
+	return tip, left, right
+}
+
 func (nl *NetworkLayer) OnRender() {
+	nl.refreshPositions()
 	nl.drawEdges()
 	nl.drawNodes()
 }
@@ -76,12 +298,22 @@ func (nl *NetworkLayer) GetTransform() (Vec2Df32, Vec2Df32) {
 func (nl *NetworkLayer) drawEdges() {
 	frame := nl.ltNode.GetFrame()
 	cameraCenter := Vec2Df32{frame.X + frame.Width/2, frame.Y + frame.Height/2}
-	cx, cy := nl.Net.GetCOM()
+	cx, cy := ednet.CenterOfMass(nl.positions)
 	com := Vec2Df32{cx, cy}
 	for sourceNodeName, targetNodeSet := range nl.Net.Adjacencies {
 		for targetNodeName, _ := range targetNodeSet {
-			nodeA := nl.Net.NodeSlice[nl.Net.NodeIndeces[sourceNodeName]]
-			nodeB := nl.Net.NodeSlice[nl.Net.NodeIndeces[targetNodeName]]
+			nodeA := nl.positions[nl.Net.NodeIndeces[sourceNodeName]]
+			nodeB := nl.positions[nl.Net.NodeIndeces[targetNodeName]]
+			edgeColor := attributeEdgeColor(nl.Net, sourceNodeName, targetNodeName, rl.Black)
+			edgeWidth := float32(1.0)
+			if nl.pathNodes != nil {
+				if nl.pathEdges[nl.Net.EdgeKey(sourceNodeName, targetNodeName)] {
+					edgeColor = rl.Gold
+					edgeWidth = 3.0
+				} else {
+					edgeColor = rl.Fade(edgeColor, 0.15)
+				}
+			}
 			posRealA := Vec2Df32{nodeA.X, nodeA.Y}
 			posAdjustedA := Vec2Df32{posRealA.X - com.X,
 				posRealA.Y - com.Y}
@@ -93,7 +325,12 @@ func (nl *NetworkLayer) drawEdges() {
 			posAdjustedB.X = cameraCenter.X + posAdjustedB.X
 			posAdjustedB.Y = cameraCenter.Y + posAdjustedB.Y
 			//TODO: don't hardcode size of circle texture
-			rl.DrawLineEx(rl.Vector2{X: posAdjustedA.X + 16, Y: posAdjustedA.Y + 16}, rl.Vector2{X: posAdjustedB.X + 16, Y: posAdjustedB.Y + 16}, 1.0, rl.Black)
+			rl.DrawLineEx(rl.Vector2{X: posAdjustedA.X + 16, Y: posAdjustedA.Y + 16}, rl.Vector2{X: posAdjustedB.X + 16, Y: posAdjustedB.Y + 16}, edgeWidth, edgeColor)
+			if nl.Net.Directed {
+				v1, v2, v3 := arrowHead(Vec2Df32{posAdjustedA.X + 16, posAdjustedA.Y + 16},
+					Vec2Df32{posAdjustedB.X + 16, posAdjustedB.Y + 16}, 6.0, 8.0)
+				rl.DrawTriangle(v1, v2, v3, edgeColor)
+			}
 		}
 	}
 }
@@ -101,29 +338,34 @@ func (nl *NetworkLayer) drawEdges() {
 func (nl *NetworkLayer) drawNodes() {
 	edamameGreen := rl.Color{62, 185, 59, 255}
 	frame := nl.ltNode.GetFrame()
-	cx, cy := nl.Net.GetCOM()
+	cx, cy := ednet.CenterOfMass(nl.positions)
 	com := Vec2Df32{cx, cy}
-	for _, n := range nl.Net.NodeSlice {
+	for _, n := range nl.positions {
 		posReal := Vec2Df32{n.X, n.Y}
 		posAdjusted := Vec2Df32{posReal.X - com.X,
 			posReal.Y - com.Y}
 		cameraCenter := Vec2Df32{frame.X + frame.Width/2, frame.Y + frame.Height/2}
 		posAdjusted.X = cameraCenter.X + posAdjusted.X
 		posAdjusted.Y = cameraCenter.Y + posAdjusted.Y
-		nodeColor := edamameGreen
+		nodeColor := attributeNodeColor(nl.Net, n.Name, edamameGreen)
+		if nl.pathNodes != nil && !nl.pathNodes[n.Name] {
+			nodeColor = rl.Fade(nodeColor, 0.15)
+		}
 		rl.DrawTexture(nl.NodeTexture.Texture, int32(posAdjusted.X), int32(posAdjusted.Y), nodeColor)
 	}
 }
 
-func (nl *NetworkLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeScale, spaceScale float32) {
+func (nl *NetworkLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeScale, nodeScale, spaceScale float32) {
+	nl.refreshPositions()
 	frame := rl.Rectangle{0.0, 0.0, float32(width), float32(height)}
 	cameraCenter := Vec2Df32{frame.X + frame.Width/2, frame.Y + frame.Height/2}
-	cx, cy := nl.Net.GetCOM()
+	cx, cy := ednet.CenterOfMass(nl.positions)
 	com := Vec2Df32{cx, cy}
 	for sourceNodeName, targetNodeSet := range nl.Net.Adjacencies {
 		for targetNodeName, _ := range targetNodeSet {
-			nodeA := nl.Net.NodeSlice[nl.Net.NodeIndeces[sourceNodeName]]
-			nodeB := nl.Net.NodeSlice[nl.Net.NodeIndeces[targetNodeName]]
+			nodeA := nl.positions[nl.Net.NodeIndeces[sourceNodeName]]
+			nodeB := nl.positions[nl.Net.NodeIndeces[targetNodeName]]
+			edgeColor := attributeEdgeColor(nl.Net, sourceNodeName, targetNodeName, rl.Black)
 			posRealA := Vec2Df32{nodeA.X, nodeA.Y}
 			posAdjustedA := Vec2Df32{posRealA.X - com.X,
 				posRealA.Y - com.Y}
@@ -142,21 +384,28 @@ func (nl *NetworkLayer) DrawEdgesImage(img *rl.Image, width, height uint, edgeSc
 			posAdjustedB.X = cameraCenter.X + posAdjustedB.X
 			posAdjustedB.Y = cameraCenter.Y + posAdjustedB.Y
 
-			edgeWidth := 10.0 //TODO: Make this an option
+			weight, _ := nl.Net.EdgeWeight(sourceNodeName, targetNodeName)
+			edgeWidth := max(weight*edgeScale, 1.0)
 
 			rl.ImageDrawLineEx(img,
 				rl.Vector2{X: posAdjustedA.X, Y: posAdjustedA.Y},
 				rl.Vector2{X: posAdjustedB.X, Y: posAdjustedB.Y},
-				int32(edgeWidth), rl.Black)
+				int32(edgeWidth), edgeColor)
+			if nl.Net.Directed {
+				v1, v2, v3 := arrowHead(posAdjustedA, posAdjustedB,
+					edgeWidth*3.0, nodeB.Radius*nodeScale)
+				rl.ImageDrawTriangle(img, v1, v2, v3, edgeColor)
+			}
 		}
 	}
 }
 
 func (nl *NetworkLayer) DrawNodesImage(img *rl.Image, width, height uint, nodeScale, spaceScale float32) {
+	nl.refreshPositions()
 	frame := rl.Rectangle{0.0, 0.0, float32(width), float32(height)}
-	cx, cy := nl.Net.GetCOM()
+	cx, cy := ednet.CenterOfMass(nl.positions)
 	com := Vec2Df32{cx, cy}
-	for _, n := range nl.Net.NodeSlice {
+	for _, n := range nl.positions {
 		posReal := Vec2Df32{n.X, n.Y}
 		posAdjusted := Vec2Df32{posReal.X - com.X,
 			posReal.Y - com.Y}
@@ -166,7 +415,7 @@ func (nl *NetworkLayer) DrawNodesImage(img *rl.Image, width, height uint, nodeSc
 		posAdjusted.X = cameraCenter.X + posAdjusted.X
 		posAdjusted.Y = cameraCenter.Y + posAdjusted.Y
 		radius := n.Radius * nodeScale
-		nodeColor := rl.NewColor(0, 0, 255, 255)
+		nodeColor := attributeNodeColor(nl.Net, n.Name, rl.NewColor(0, 0, 255, 255))
 		rl.ImageDrawCircle(img, int32(posAdjusted.X), int32(posAdjusted.Y), int32(radius), nodeColor)
 		rl.ImageDrawText(img, int32(posAdjusted.X), int32(posAdjusted.Y), n.Name, 8, rl.White)
 	}
@@ -3,8 +3,8 @@ package app
 import (
 	"encoding/csv"
 	"log"
-	"math/rand"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -21,11 +21,13 @@ const (
 )
 
 type UILayer struct {
-	currentState UIState
-	currentFPS   int
-	origin       Vec2Df32
-	size         Vec2Df32
-	ltNode       *LayerTreeNode
+	currentState  UIState
+	currentFPS    int
+	directedEdges bool
+	seed          int64
+	origin        Vec2Df32
+	size          Vec2Df32
+	ltNode        *LayerTreeNode
 }
 
 func (u *UILayer) SetLTNode(ltNode *LayerTreeNode) {
@@ -58,13 +60,14 @@ func (u *UILayer) OnRender() {
 		u.ltNode.AddChild(&fileLoadLayer)
 	}
 
+	u.directedEdges = u.drawDirectedCheckBox()
 	loadEdgeFile := u.drawEdgeButton()
 	if loadEdgeFile && u.currentState == UIMain {
 		var fileLoadLayer FileLoadLayer
 		fileLoadLayer.SetTransform(Vec2Df32{0.2, 0.2}, Vec2Df32{0.6, 0.6})
 		loadCallback := func(fname string) {
 			u.currentState = UIMain
-			u.loadEdgeData(fname)
+			u.loadEdgeData(fname, u.directedEdges)
 		}
 		fileLoadLayer.SetCallback(loadCallback)
 		u.currentState = UILoad
@@ -82,6 +85,46 @@ func (u *UILayer) OnRender() {
 		}
 	}
 
+	for _, child := range u.ltNode.Children {
+		value, isType := child.Data.(*NetworkLayer)
+		if isType {
+			selected := u.drawLayoutSelector(value.LayoutName)
+			if selected != value.LayoutName && u.currentState == UIMain {
+				err := value.SetLayout(selected)
+				if err != nil {
+					log.Print(err)
+				}
+			}
+		}
+	}
+
+	for _, child := range u.ltNode.Children {
+		value, isType := child.Data.(*NetworkLayer)
+		if isType {
+			pack := u.drawPackCheckBox(value.PackComponents)
+			if pack != value.PackComponents && u.currentState == UIMain && !value.Running() {
+				value.PackComponents = pack
+				err := value.SetLayout(value.LayoutName)
+				if err != nil {
+					log.Print(err)
+				}
+			}
+		}
+	}
+
+	overlaps := u.drawOverlapButton()
+	if overlaps && u.currentState == UIMain {
+		for _, child := range u.ltNode.Children {
+			value, isType := child.Data.(*NetworkLayer)
+			if isType {
+				err := value.RemoveOverlaps()
+				if err != nil {
+					log.Print(err)
+				}
+			}
+		}
+	}
+
 	export := u.drawExportButton()
 	if export && u.currentState == UIMain {
 		//TODO: Make these options the user can select
@@ -95,7 +138,7 @@ func (u *UILayer) OnRender() {
 		for _, child := range u.ltNode.Children {
 			value, isType := child.Data.(*NetworkLayer)
 			if isType {
-				value.DrawEdgesImage(img, imgSize, imgSize, edgeScale, spaceScale)
+				value.DrawEdgesImage(img, imgSize, imgSize, edgeScale, nodeScale, spaceScale)
 				value.DrawNodesImage(img, imgSize, imgSize, nodeScale, spaceScale)
 			}
 		}
@@ -120,6 +163,33 @@ func (u *UILayer) drawStats() {
 	gui.GroupBox(rl.Rectangle{infoBoxOrigin.X, infoBoxOrigin.Y, infoBoxSize.X, infoBoxSize.Y}, "Info")
 	fpsStr := strconv.Itoa(u.currentFPS)
 	rl.DrawText("FPS: "+fpsStr, int32(infoBoxOrigin.X+8), int32(infoBoxOrigin.Y+8), 16, rl.White)
+
+	//energy of the latest layout step
+	for _, child := range u.ltNode.Children {
+		value, isType := child.Data.(*NetworkLayer)
+		if isType && value.Monitor != nil {
+			stats, stepped := value.Monitor.Last()
+			if !stepped {
+				continue
+			}
+			energyStr := strconv.FormatFloat(stats.Energy, 'g', 4, 64)
+			rl.DrawText("Energy: "+energyStr, int32(infoBoxOrigin.X+8), int32(infoBoxOrigin.Y+0.5*infoBoxSize.Y+8), 16, rl.White)
+			moveStr := strconv.FormatFloat(float64(stats.MaxDisplacement), 'g', 4, 32)
+			rl.DrawText("Max move: "+moveStr, int32(infoBoxOrigin.X+8), int32(infoBoxOrigin.Y+0.5*infoBoxSize.Y+28), 16, rl.White)
+			if value.Monitor.Converged() {
+				rl.DrawText("Converged", int32(infoBoxOrigin.X+8), int32(infoBoxOrigin.Y+0.5*infoBoxSize.Y+48), 16, rl.White)
+			}
+		}
+	}
+
+	//length of the highlighted shortest path
+	for _, child := range u.ltNode.Children {
+		value, isType := child.Data.(*NetworkLayer)
+		if isType && value.PathTarget != "" && value.PathLength > 0 {
+			lengthStr := strconv.FormatFloat(float64(value.PathLength), 'g', 4, 32)
+			rl.DrawText("Path length: "+lengthStr, int32(infoBoxOrigin.X+8), int32(infoBoxOrigin.Y+0.5*infoBoxSize.Y+68), 16, rl.White)
+		}
+	}
 }
 
 func (u *UILayer) drawNodeButton() bool {
@@ -162,6 +232,44 @@ func (u *UILayer) drawEdgeButton() bool {
 	return loadFilePressed
 }
 
+func (u *UILayer) drawDirectedCheckBox() bool {
+	screenWidth := float32(rl.GetScreenWidth())
+	screenHeight := float32(rl.GetScreenHeight())
+
+	pixelOrigin := Vec2Di{int(u.origin.X * screenWidth), int(u.origin.Y * screenHeight)}
+	pixelSize := Vec2Di{int(u.size.X * screenWidth), int(u.size.Y * screenHeight)}
+	infoBoxOrigin := Vec2Df32{float32(pixelOrigin.X) + 0.025*float32(pixelSize.X),
+		float32(pixelOrigin.Y) + 0.05*float32(pixelSize.Y)}
+	infoBoxSize := Vec2Df32{0.15 * float32(pixelSize.X),
+		0.9 * float32(pixelSize.Y)}
+
+	checkBoxOrigin := Vec2Df32{X: infoBoxOrigin.X + 0.1*infoBoxSize.X,
+		Y: infoBoxOrigin.Y + 0.205*infoBoxSize.Y}
+	checkBoxSize := Vec2Df32{X: 0.025 * infoBoxSize.Y,
+		Y: 0.025 * infoBoxSize.Y}
+
+	return gui.CheckBox(rl.Rectangle{checkBoxOrigin.X, checkBoxOrigin.Y, checkBoxSize.X, checkBoxSize.Y}, "Directed", u.directedEdges)
+}
+
+func (u *UILayer) drawPackCheckBox(checked bool) bool {
+	screenWidth := float32(rl.GetScreenWidth())
+	screenHeight := float32(rl.GetScreenHeight())
+
+	pixelOrigin := Vec2Di{int(u.origin.X * screenWidth), int(u.origin.Y * screenHeight)}
+	pixelSize := Vec2Di{int(u.size.X * screenWidth), int(u.size.Y * screenHeight)}
+	infoBoxOrigin := Vec2Df32{float32(pixelOrigin.X) + 0.025*float32(pixelSize.X),
+		float32(pixelOrigin.Y) + 0.05*float32(pixelSize.Y)}
+	infoBoxSize := Vec2Df32{0.15 * float32(pixelSize.X),
+		0.9 * float32(pixelSize.Y)}
+
+	checkBoxOrigin := Vec2Df32{X: infoBoxOrigin.X + 0.1*infoBoxSize.X,
+		Y: infoBoxOrigin.Y + 0.305*infoBoxSize.Y}
+	checkBoxSize := Vec2Df32{X: 0.025 * infoBoxSize.Y,
+		Y: 0.025 * infoBoxSize.Y}
+
+	return gui.CheckBox(rl.Rectangle{checkBoxOrigin.X, checkBoxOrigin.Y, checkBoxSize.X, checkBoxSize.Y}, "Pack Components", checked)
+}
+
 func (u *UILayer) drawRunLayoutButton() bool {
 	screenWidth := float32(rl.GetScreenWidth())
 	screenHeight := float32(rl.GetScreenHeight())
@@ -202,6 +310,51 @@ func (u *UILayer) drawExportButton() bool {
 	return exportPressed
 }
 
+func (u *UILayer) drawOverlapButton() bool {
+	screenWidth := float32(rl.GetScreenWidth())
+	screenHeight := float32(rl.GetScreenHeight())
+
+	pixelOrigin := Vec2Di{int(u.origin.X * screenWidth), int(u.origin.Y * screenHeight)}
+	pixelSize := Vec2Di{int(u.size.X * screenWidth), int(u.size.Y * screenHeight)}
+	infoBoxOrigin := Vec2Df32{float32(pixelOrigin.X) + 0.025*float32(pixelSize.X),
+		float32(pixelOrigin.Y) + 0.05*float32(pixelSize.Y)}
+	infoBoxSize := Vec2Df32{0.15 * float32(pixelSize.X),
+		0.9 * float32(pixelSize.Y)}
+
+	buttonOrigin := Vec2Df32{X: infoBoxOrigin.X + 0.1*infoBoxSize.X,
+		Y: infoBoxOrigin.Y + 0.4025*infoBoxSize.Y}
+	buttonSize := Vec2Df32{X: 0.8 * infoBoxSize.X,
+		Y: 0.045 * infoBoxSize.Y}
+
+	return gui.Button(rl.Rectangle{buttonOrigin.X, buttonOrigin.Y, buttonSize.X, buttonSize.Y}, "Remove Overlaps")
+}
+
+func (u *UILayer) drawLayoutSelector(current string) string {
+	screenWidth := float32(rl.GetScreenWidth())
+	screenHeight := float32(rl.GetScreenHeight())
+
+	pixelOrigin := Vec2Di{int(u.origin.X * screenWidth), int(u.origin.Y * screenHeight)}
+	pixelSize := Vec2Di{int(u.size.X * screenWidth), int(u.size.Y * screenHeight)}
+	infoBoxOrigin := Vec2Df32{float32(pixelOrigin.X) + 0.025*float32(pixelSize.X),
+		float32(pixelOrigin.Y) + 0.05*float32(pixelSize.Y)}
+	infoBoxSize := Vec2Df32{0.15 * float32(pixelSize.X),
+		0.9 * float32(pixelSize.Y)}
+
+	selectorOrigin := Vec2Df32{X: infoBoxOrigin.X + 0.1*infoBoxSize.X,
+		Y: infoBoxOrigin.Y + 0.45*infoBoxSize.Y}
+	selectorSize := Vec2Df32{X: 0.8 * infoBoxSize.X,
+		Y: 0.05 * infoBoxSize.Y}
+
+	names := ednet.LayoutNames()
+	active := slices.Index(names, current)
+	if active == -1 {
+		active = 0
+	}
+	active = int(gui.ComboBox(rl.Rectangle{selectorOrigin.X, selectorOrigin.Y, selectorSize.X, selectorSize.Y},
+		strings.Join(names, ";"), int32(active)))
+	return names[active]
+}
+
 func (u *UILayer) SetTransform(origin, size Vec2Df32) {
 	u.origin = origin
 	u.size = size
@@ -235,13 +388,15 @@ func (u *UILayer) loadNodeData(fname string) {
 	// A,1.0,40,94,150,255
 
 	for _, netLayer := range netLayers {
+		netLayer.StopLayout()
 		netLayer.Net = ednet.NewSpatialNet()
+		netLayer.Net.Seed(u.seed)
 		for lineIDX, record := range records {
 			//skip the header
 			if lineIDX == 0 {
 				continue
 			}
-			if len(record) != 6 {
+			if len(record) < 2 {
 				log.Fatal("Bad node data file!")
 			}
 			name := record[0]
@@ -249,33 +404,27 @@ func (u *UILayer) loadNodeData(fname string) {
 			if err != nil {
 				log.Fatal(err)
 			}
-			//TODO: Store color data
-			// r, err := strconv.ParseUint(record[2], 10, 8)
-			// if err != nil {
-			// 	log.Fatal(err)
-			// }
-			// g, err := strconv.ParseUint(record[3], 10, 8)
-			// if err != nil {
-			// 	log.Fatal(err)
-			// }
-			// b, err := strconv.ParseUint(record[4], 10, 8)
-			// if err != nil {
-			// 	log.Fatal(err)
-			// }
-			// a, err := strconv.ParseUint(record[5], 10, 8)
-			// if err != nil {
-			// 	log.Fatal(err)
-			// }
 			netLayer.Net.AddNode(name)
 			var node *ednet.SpatialNetNode = &netLayer.Net.NodeSlice[len(netLayer.Net.NodeSlice) - 1]
-			node.X = (100.0 * rand.Float32()) - 50.0
-			node.Y = (100.0 * rand.Float32()) - 50.0
+			node.X = (100.0 * netLayer.Net.Rng.Float32()) - 50.0
+			node.Y = (100.0 * netLayer.Net.Rng.Float32()) - 50.0
 			node.Radius = float32(radius)
 		}
+		positionColumns, err := applyNodePositions(netLayer.Net, records)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//extra columns, including r,g,b,a, become node attributes
+		err = storeAttributes(netLayer.Net.NodeAttributes, records, 2, positionColumns, func(record []string) string {
+			return record[0]
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 }
 
-func (u *UILayer) loadEdgeData(fname string) {
+func (u *UILayer) loadEdgeData(fname string, directed bool) {
 	content, err := os.ReadFile(fname)
 	if err != nil {
 		log.Fatal(err)
@@ -298,26 +447,29 @@ func (u *UILayer) loadEdgeData(fname string) {
 	for _, netLayer := range netLayers {
 
 		//Reset edge data in the SpatialNet
-		netLayer.Net.Adjacencies = make(ednet.EdgeSet)
-		for _, node := range netLayer.Net.NodeSlice{
-			netLayer.Net.Adjacencies[node.Name] = make(map[string]struct{})
-		}
+		netLayer.StopLayout()
+		netLayer.Net.ResetEdges(directed)
 		for lineIDX, record := range records {
 			//skip the header
 			if lineIDX == 0 {
 				continue
 			}
-			if len(record) != 3 {
+			if len(record) < 3 {
 				log.Fatal("Bad edge data file!")
 			}
 			nameA := record[0]
 			nameB := record[1]
-			//TODO: Store edge width
-			// width, err := strconv.ParseFloat(record[2], 32)
-			// if err != nil {
-			// 	log.Fatal(err)
-			// }
-			netLayer.Net.AddEdge(nameA, nameB)
+			width, err := strconv.ParseFloat(record[2], 32)
+			if err != nil {
+				log.Fatal(err)
+			}
+			netLayer.Net.AddWeightedEdge(nameA, nameB, float32(width))
+		}
+		err = storeAttributes(netLayer.Net.EdgeAttributes, records, 3, nil, func(record []string) ednet.EdgeKey {
+			return netLayer.Net.EdgeKey(record[0], record[1])
+		})
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 }
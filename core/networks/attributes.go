@@ -0,0 +1,399 @@
+package networks
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AttributeType is the type of the values stored in an attribute column
+type AttributeType int
+
+const (
+	AttributeString AttributeType = iota
+	AttributeFloat
+	AttributeInt
+	AttributeBool
+	AttributeColor
+)
+
+func (t AttributeType) String() string {
+	switch t {
+	case AttributeFloat:
+		return "float"
+	case AttributeInt:
+		return "int"
+	case AttributeBool:
+		return "bool"
+	case AttributeColor:
+		return "color"
+	}
+	return "string"
+}
+
+// Color is an RGBA color, kept separate from raylib so the
+// networks package doesn't depend on it
+type Color struct {
+	R, G, B, A uint8
+}
+
+// EdgeKey identifies an edge in an edge AttributeTable.
+// Use SpatialNet.EdgeKey to build one, so both directions of
+// an undirected edge map to the same key.
+type EdgeKey struct {
+	Source, Target string
+}
+
+// EdgeKey returns the key of the edge between nameA and nameB
+func (n *SpatialNet) EdgeKey(nameA, nameB string) EdgeKey {
+	if !n.Directed && nameB < nameA {
+		nameA, nameB = nameB, nameA
+	}
+	return EdgeKey{Source: nameA, Target: nameB}
+}
+
+// StoreNodeAttribute saves per-node values, such as a centrality,
+// as the float node attribute name
+func (n *SpatialNet) StoreNodeAttribute(name string, values map[string]float32) error {
+	err := n.NodeAttributes.Define(name, AttributeFloat)
+	if err != nil {
+		return err
+	}
+	for node, value := range values {
+		if n.ContainsNode(node) {
+			n.NodeAttributes.SetFloat(node, name, value)
+		}
+	}
+	return nil
+}
+
+// attributeColumn holds the values of one attribute,
+// only the map matching kind is used
+type attributeColumn[K comparable] struct {
+	kind    AttributeType
+	strings map[K]string
+	floats  map[K]float32
+	ints    map[K]int
+	bools   map[K]bool
+	colors  map[K]Color
+}
+
+func (c *attributeColumn[K]) delete(key K) {
+	delete(c.strings, key)
+	delete(c.floats, key)
+	delete(c.ints, key)
+	delete(c.bools, key)
+	delete(c.colors, key)
+}
+
+func (c *attributeColumn[K]) has(key K) bool {
+	var exists bool
+	switch c.kind {
+	case AttributeString:
+		_, exists = c.strings[key]
+	case AttributeFloat:
+		_, exists = c.floats[key]
+	case AttributeInt:
+		_, exists = c.ints[key]
+	case AttributeBool:
+		_, exists = c.bools[key]
+	case AttributeColor:
+		_, exists = c.colors[key]
+	}
+	return exists
+}
+
+// AttributeTable stores typed columns of values keyed by attribute name.
+// SpatialNet keeps one for nodes, keyed by node name, and one for
+// edges, keyed by EdgeKey. Setting a value of an attribute that does not
+// exist yet defines it with the type of the value.
+type AttributeTable[K comparable] struct {
+	columns map[string]*attributeColumn[K]
+}
+
+func NewAttributeTable[K comparable]() *AttributeTable[K] {
+	return &AttributeTable[K]{columns: make(map[string]*attributeColumn[K])}
+}
+
+// Define adds an empty attribute column of the given type.
+// Defining an attribute again with the same type does nothing.
+func (t *AttributeTable[K]) Define(name string, kind AttributeType) error {
+	if column, exists := t.columns[name]; exists {
+		if column.kind != kind {
+			return errors.New("attribute " + name + " is already defined as " + column.kind.String())
+		}
+		return nil
+	}
+	if t.columns == nil {
+		t.columns = make(map[string]*attributeColumn[K])
+	}
+	column := &attributeColumn[K]{kind: kind}
+	switch kind {
+	case AttributeString:
+		column.strings = make(map[K]string)
+	case AttributeFloat:
+		column.floats = make(map[K]float32)
+	case AttributeInt:
+		column.ints = make(map[K]int)
+	case AttributeBool:
+		column.bools = make(map[K]bool)
+	case AttributeColor:
+		column.colors = make(map[K]Color)
+	default:
+		return errors.New("unknown attribute type for " + name)
+	}
+	t.columns[name] = column
+	return nil
+}
+
+// Type returns the type of an attribute and whether it is defined
+func (t *AttributeTable[K]) Type(name string) (AttributeType, bool) {
+	column, exists := t.columns[name]
+	if !exists {
+		return AttributeString, false
+	}
+	return column.kind, true
+}
+
+// Names returns the names of every defined attribute in sorted order
+func (t *AttributeTable[K]) Names() []string {
+	names := make([]string, 0, len(t.columns))
+	for name := range t.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether key has a value for the attribute
+func (t *AttributeTable[K]) Has(key K, name string) bool {
+	column, exists := t.columns[name]
+	return exists && column.has(key)
+}
+
+// Delete removes every attribute value of key
+func (t *AttributeTable[K]) Delete(key K) {
+	for _, column := range t.columns {
+		column.delete(key)
+	}
+}
+
+// copyKey copies every attribute value of key into dst,
+// defining the attributes there if needed
+func (t *AttributeTable[K]) copyKey(dst *AttributeTable[K], key K) {
+	for name, column := range t.columns {
+		if !column.has(key) {
+			continue
+		}
+		switch column.kind {
+		case AttributeString:
+			dst.SetString(key, name, column.strings[key])
+		case AttributeFloat:
+			dst.SetFloat(key, name, column.floats[key])
+		case AttributeInt:
+			dst.SetInt(key, name, column.ints[key])
+		case AttributeBool:
+			dst.SetBool(key, name, column.bools[key])
+		case AttributeColor:
+			dst.SetColor(key, name, column.colors[key])
+		}
+	}
+}
+
+// column returns the column of an attribute, defining it if needed
+func (t *AttributeTable[K]) column(name string, kind AttributeType) (*attributeColumn[K], error) {
+	err := t.Define(name, kind)
+	if err != nil {
+		return nil, err
+	}
+	return t.columns[name], nil
+}
+
+func (t *AttributeTable[K]) SetString(key K, name, value string) error {
+	column, err := t.column(name, AttributeString)
+	if err != nil {
+		return err
+	}
+	column.strings[key] = value
+	return nil
+}
+
+func (t *AttributeTable[K]) SetFloat(key K, name string, value float32) error {
+	column, err := t.column(name, AttributeFloat)
+	if err != nil {
+		return err
+	}
+	column.floats[key] = value
+	return nil
+}
+
+func (t *AttributeTable[K]) SetInt(key K, name string, value int) error {
+	column, err := t.column(name, AttributeInt)
+	if err != nil {
+		return err
+	}
+	column.ints[key] = value
+	return nil
+}
+
+func (t *AttributeTable[K]) SetBool(key K, name string, value bool) error {
+	column, err := t.column(name, AttributeBool)
+	if err != nil {
+		return err
+	}
+	column.bools[key] = value
+	return nil
+}
+
+func (t *AttributeTable[K]) SetColor(key K, name string, value Color) error {
+	column, err := t.column(name, AttributeColor)
+	if err != nil {
+		return err
+	}
+	column.colors[key] = value
+	return nil
+}
+
+// String returns the value of a string attribute, and false if
+// the attribute is missing, has another type, or key has no value
+func (t *AttributeTable[K]) String(key K, name string) (string, bool) {
+	column, exists := t.columns[name]
+	if !exists || column.kind != AttributeString {
+		return "", false
+	}
+	value, exists := column.strings[key]
+	return value, exists
+}
+
+// Float returns the value of a float attribute. Int attributes
+// are converted, so any numeric attribute can be read as a float.
+func (t *AttributeTable[K]) Float(key K, name string) (float32, bool) {
+	column, exists := t.columns[name]
+	if !exists {
+		return 0.0, false
+	}
+	switch column.kind {
+	case AttributeFloat:
+		value, exists := column.floats[key]
+		return value, exists
+	case AttributeInt:
+		value, exists := column.ints[key]
+		return float32(value), exists
+	}
+	return 0.0, false
+}
+
+func (t *AttributeTable[K]) Int(key K, name string) (int, bool) {
+	column, exists := t.columns[name]
+	if !exists || column.kind != AttributeInt {
+		return 0, false
+	}
+	value, exists := column.ints[key]
+	return value, exists
+}
+
+func (t *AttributeTable[K]) Bool(key K, name string) (bool, bool) {
+	column, exists := t.columns[name]
+	if !exists || column.kind != AttributeBool {
+		return false, false
+	}
+	value, exists := column.bools[key]
+	return value, exists
+}
+
+func (t *AttributeTable[K]) Color(key K, name string) (Color, bool) {
+	column, exists := t.columns[name]
+	if !exists || column.kind != AttributeColor {
+		return Color{}, false
+	}
+	value, exists := column.colors[key]
+	return value, exists
+}
+
+// SetParsed parses value as the type of an attribute and stores it.
+// Colors are written as "r g b a" with values from 0 to 255.
+func (t *AttributeTable[K]) SetParsed(key K, name, value string) error {
+	kind, exists := t.Type(name)
+	if !exists {
+		return errors.New("attribute " + name + " is not defined")
+	}
+	switch kind {
+	case AttributeFloat:
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		return t.SetFloat(key, name, float32(f))
+	case AttributeInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		return t.SetInt(key, name, i)
+	case AttributeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		return t.SetBool(key, name, b)
+	case AttributeColor:
+		c, err := ParseColor(strings.Fields(value))
+		if err != nil {
+			return err
+		}
+		return t.SetColor(key, name, c)
+	}
+	return t.SetString(key, name, value)
+}
+
+// ParseColor parses r, g, b and optionally a from 0 to 255,
+// alpha is 255 when left out
+func ParseColor(channels []string) (Color, error) {
+	if len(channels) != 3 && len(channels) != 4 {
+		return Color{}, errors.New("a color needs 3 or 4 channels")
+	}
+	values := [4]uint8{255, 255, 255, 255}
+	for i, channel := range channels {
+		v, err := strconv.ParseUint(strings.TrimSpace(channel), 10, 8)
+		if err != nil {
+			return Color{}, err
+		}
+		values[i] = uint8(v)
+	}
+	return Color{R: values[0], G: values[1], B: values[2], A: values[3]}, nil
+}
+
+// InferAttributeType returns the narrowest type every value
+// can be parsed as, checking int, then float, then bool.
+// Empty values are ignored.
+func InferAttributeType(values []string) AttributeType {
+	isInt, isFloat, isBool := true, true, true
+	seen := false
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		seen = true
+		if _, err := strconv.Atoi(value); err != nil {
+			isInt = false
+		}
+		if _, err := strconv.ParseFloat(value, 32); err != nil {
+			isFloat = false
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			isBool = false
+		}
+	}
+	switch {
+	case !seen:
+		return AttributeString
+	case isInt:
+		return AttributeInt
+	case isFloat:
+		return AttributeFloat
+	case isBool:
+		return AttributeBool
+	}
+	return AttributeString
+}
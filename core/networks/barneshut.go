@@ -0,0 +1,212 @@
+package networks
+
+import (
+	"math"
+	"sync"
+)
+
+// maximum depth of the quadtree, nodes that share a position
+// past this depth are lumped together into a single leaf
+const maxQuadTreeDepth = 32
+
+// QuadTree is a Barnes-Hut quadtree over the nodes of a SpatialNet.
+// Each cell stores the total mass (node count, unless weighted) and
+// center of mass of the nodes inside of it.
+type QuadTree struct {
+	//Min corner and side length of this square cell
+	X, Y, Size float32
+
+	//Total mass of the nodes in the cell and their center of mass
+	Mass   float32
+	CX, CY float32
+
+	//Index in NodeSlice of the node stored in a leaf,
+	//-1 for internal cells and leaves holding several nodes
+	Node int
+
+	Children [4]*QuadTree
+}
+
+// NewQuadTree builds a quadtree over all the nodes in NodeSlice
+func (n *SpatialNet) NewQuadTree() *QuadTree {
+	return n.NewWeightedQuadTree(nil)
+}
+
+// NewWeightedQuadTree builds a quadtree where the node at index i has
+// mass masses[i]. A nil masses slice gives every node a mass of 1.
+func (n *SpatialNet) NewWeightedQuadTree(masses []float32) *QuadTree {
+	if len(n.NodeSlice) == 0 {
+		return &QuadTree{Node: -1}
+	}
+
+	minX, minY := n.NodeSlice[0].X, n.NodeSlice[0].Y
+	maxX, maxY := minX, minY
+	for _, node := range n.NodeSlice {
+		minX = min(minX, node.X)
+		minY = min(minY, node.Y)
+		maxX = max(maxX, node.X)
+		maxY = max(maxY, node.Y)
+	}
+	size := max(maxX-minX, maxY-minY, 1.0)
+
+	qt := &QuadTree{X: minX, Y: minY, Size: size * 1.01, Node: -1}
+	for i := range n.NodeSlice {
+		qt.insert(n.NodeSlice, masses, i, 0)
+	}
+	return qt
+}
+
+func (qt *QuadTree) isLeaf() bool {
+	return qt.Children[0] == nil
+}
+
+// contains reports whether the point lies inside the cell, using the
+// same half open bounds that insert uses to pick a quadrant
+func (qt *QuadTree) contains(x, y float32) bool {
+	return x >= qt.X && x < qt.X+qt.Size && y >= qt.Y && y < qt.Y+qt.Size
+}
+
+func (qt *QuadTree) quadrant(x, y float32) int {
+	half := qt.Size / 2
+	q := 0
+	if x >= qt.X+half {
+		q += 1
+	}
+	if y >= qt.Y+half {
+		q += 2
+	}
+	return q
+}
+
+func (qt *QuadTree) subdivide() {
+	half := qt.Size / 2
+	for q := range 4 {
+		qt.Children[q] = &QuadTree{X: qt.X + float32(q%2)*half,
+			Y:    qt.Y + float32(q/2)*half,
+			Size: half,
+			Node: -1}
+	}
+}
+
+func (qt *QuadTree) insert(nodes []SpatialNetNode, masses []float32, idx int, depth int) {
+	node := &nodes[idx]
+	var mass float32 = 1.0
+	if masses != nil {
+		mass = masses[idx]
+	}
+
+	//empty leaf, store the node here
+	if qt.Mass == 0 && qt.isLeaf() && qt.Node == -1 {
+		qt.Mass = mass
+		qt.CX, qt.CY = node.X, node.Y
+		qt.Node = idx
+		return
+	}
+
+	//update the center of mass for this cell
+	total := qt.Mass + mass
+	if total > 0 {
+		qt.CX = (qt.CX*qt.Mass + node.X*mass) / total
+		qt.CY = (qt.CY*qt.Mass + node.Y*mass) / total
+	}
+	qt.Mass = total
+
+	if depth >= maxQuadTreeDepth {
+		qt.Node = -1
+		return
+	}
+
+	if qt.isLeaf() {
+		qt.subdivide()
+		if qt.Node != -1 {
+			old := &nodes[qt.Node]
+			qt.Children[qt.quadrant(old.X, old.Y)].insert(nodes, masses, qt.Node, depth+1)
+			qt.Node = -1
+		}
+	}
+	qt.Children[qt.quadrant(node.X, node.Y)].insert(nodes, masses, idx, depth+1)
+}
+
+// RepulsionForce approximates the total repulsive force acting on the
+// node at index idx. Cells whose size to distance ratio is below theta
+// are treated as a single point mass, except for the cells holding the
+// node itself, which are always opened so it doesn't push on itself.
+func (qt *QuadTree) RepulsionForce(nodes []SpatialNetNode, idx int, repulsion, theta float32) (float32, float32) {
+	if qt.Mass == 0 || qt.Node == idx {
+		return 0.0, 0.0
+	}
+
+	node := &nodes[idx]
+	dx := qt.CX - node.X
+	dy := qt.CY - node.Y
+	dist := float32(math.Hypot(float64(dx), float64(dy)))
+
+	if qt.isLeaf() || (qt.Size/dist < theta && !qt.contains(node.X, node.Y)) {
+		//coincident nodes (or a max depth leaf holding this node)
+		//have no direction to push in
+		if dist == 0 {
+			return 0.0, 0.0
+		}
+		clamped := max(dist, 1.0)
+		f := -1.0 * qt.Mass * repulsion / (clamped * clamped)
+		return f * dx / dist, f * dy / dist
+	}
+
+	var fx, fy float32 = 0.0, 0.0
+	for _, child := range qt.Children {
+		cfx, cfy := child.RepulsionForce(nodes, idx, repulsion, theta)
+		fx += cfx
+		fy += cfy
+	}
+	return fx, fy
+}
+
+// SpringUpdateBarnesHut performs one step of the same spring model as
+// SpringUpdateParallel, but approximates repulsion with a quadtree so a
+// step costs O(n log n) instead of O(n^2). A theta of 0 gives the exact
+// result, larger values trade accuracy for speed (0.5 - 1.0 is typical).
+func (n *SpatialNet) SpringUpdateBarnesHut(k,
+	stepSize,
+	equilibriumDist,
+	repulsion,
+	friction,
+	theta float32,
+	maxWorkers uint,
+	weightMode WeightMode) {
+
+	actualWorkers := max(min(maxWorkers, uint(len(n.NodeSlice))), 1)
+
+	qt := n.NewQuadTree()
+	csr := n.CSR()
+
+	var wg = &sync.WaitGroup{}
+	queue := make(chan int, actualWorkers)
+
+	worker := func(wg *sync.WaitGroup, queue chan int) {
+		defer wg.Done()
+		for i := range queue {
+			nodeA := &n.NodeSlice[i]
+			fx, fy := qt.RepulsionForce(n.NodeSlice, i, repulsion, theta)
+
+			sfx, sfy := n.neighborSpringForce(i, csr.NodeNeighbors(i), csr.NodeWeights(i), k, equilibriumDist, repulsion, weightMode)
+			nodeA.Vx += stepSize * (fx + sfx)
+			nodeA.Vy += stepSize * (fy + sfy)
+		}
+	}
+
+	for range actualWorkers {
+		wg.Add(1)
+		go worker(wg, queue)
+	}
+	for i := range n.NodeSlice {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	for i := range len(n.NodeSlice) {
+		n.NodeSlice[i].move(stepSize*n.NodeSlice[i].Vx, stepSize*n.NodeSlice[i].Vy)
+		n.NodeSlice[i].Vx -= stepSize * friction * n.NodeSlice[i].Vx
+		n.NodeSlice[i].Vy -= stepSize * friction * n.NodeSlice[i].Vy
+	}
+}
@@ -0,0 +1,43 @@
+package networks
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestBarnesHutExactAtThetaZero(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	n := NewRandomSpatialNet(200, 0.0, rng)
+	for i := range n.NodeSlice {
+		n.NodeSlice[i].X = rng.Float32()*400 - 200
+		n.NodeSlice[i].Y = rng.Float32()*400 - 200
+	}
+	qt := n.NewQuadTree()
+	for i := range n.NodeSlice {
+		wantX, wantY := n.repulsionForce(i, 80.0)
+		gotX, gotY := qt.RepulsionForce(n.NodeSlice, i, 80.0, 0.0)
+		scale := max(1.0, math.Hypot(float64(wantX), float64(wantY)))
+		if math.Hypot(float64(gotX-wantX), float64(gotY-wantY)) > 1e-4*scale {
+			t.Fatalf("node %d: force (%v, %v), all pairs give (%v, %v)", i, gotX, gotY, wantX, wantY)
+		}
+	}
+}
+
+func TestBarnesHutOpensOwnCell(t *testing.T) {
+	//with a huge theta every cell would be approximated, but the cells
+	//holding the node must still be opened down to the other nodes
+	n := NewSpatialNet()
+	n.AddNode("a")
+	n.AddNode("b")
+	n.NodeSlice[0].X, n.NodeSlice[0].Y = 0.0, 0.0
+	n.NodeSlice[1].X, n.NodeSlice[1].Y = 10.0, 3.0
+	qt := n.NewQuadTree()
+	for i := range n.NodeSlice {
+		wantX, wantY := n.repulsionForce(i, 80.0)
+		gotX, gotY := qt.RepulsionForce(n.NodeSlice, i, 80.0, 1e9)
+		if math.Hypot(float64(gotX-wantX), float64(gotY-wantY)) > 1e-4 {
+			t.Errorf("node %d: force (%v, %v), all pairs give (%v, %v)", i, gotX, gotY, wantX, wantY)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package networks
+
+import (
+	"errors"
+	"math"
+)
+
+// Centralities are returned keyed by node name. Degree, closeness,
+// betweenness and eigenvector centrality treat edges as undirected,
+// PageRank follows edge directions when the network is directed.
+// Use StoreNodeAttribute to keep a result for colouring or sizing.
+
+// byName converts values indexed by position in NodeSlice to values keyed by name
+func (n *SpatialNet) byName(values []float64) map[string]float32 {
+	result := make(map[string]float32, len(values))
+	for i, v := range values {
+		result[n.NodeSlice[i].Name] = float32(v)
+	}
+	return result
+}
+
+// DegreeCentrality returns the fraction of the other nodes each node shares an edge with
+func (n *SpatialNet) DegreeCentrality() map[string]float32 {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+	values := make([]float64, count)
+	if count < 2 {
+		return n.byName(values)
+	}
+	for i := range count {
+		degree := csr.Degree(i)
+		if csr.HasEdge(i, i) {
+			degree--
+		}
+		values[i] = float64(degree) / float64(count-1)
+	}
+	return n.byName(values)
+}
+
+// ClosenessCentrality returns the inverse of the average hop distance from
+// each node to the nodes it can reach, scaled by the fraction of nodes it
+// can reach so nodes in small components don't score highly (Wasserman
+// and Faust). Nodes that reach nothing have closeness 0.
+func (n *SpatialNet) ClosenessCentrality(maxWorkers uint) map[string]float32 {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+	values := make([]float64, count)
+	parallelFor(count, maxWorkers, func(source int) {
+		dist := make([]int, count)
+		for i := range dist {
+			dist[i] = -1
+		}
+		dist[source] = 0
+		queue := []int{source}
+		total, reached := 0, 0
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			total += dist[current]
+			reached++
+			for _, nbr := range csr.NodeNeighbors(current) {
+				if dist[nbr] == -1 {
+					dist[nbr] = dist[current] + 1
+					queue = append(queue, nbr)
+				}
+			}
+		}
+		if total > 0 {
+			others := float64(reached - 1)
+			values[source] = (others / float64(total)) * (others / float64(count-1))
+		}
+	})
+	return n.byName(values)
+}
+
+// BetweennessCentrality returns the fraction of shortest paths between
+// other pairs of nodes that pass through each node, using Brandes'
+// algorithm with the sources split across maxWorkers go routines.
+// Without normalization the raw count of paths is returned.
+func (n *SpatialNet) BetweennessCentrality(normalized bool, maxWorkers uint) map[string]float32 {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+	workers := int(max(min(maxWorkers, uint(count)), 1))
+
+	//every worker sums into its own row, rows are added up at the end
+	partial := make([][]float64, workers)
+	parallelFor(workers, uint(workers), func(w int) {
+		centrality := make([]float64, count)
+		sigma := make([]float64, count)
+		delta := make([]float64, count)
+		dist := make([]int, count)
+		preds := make([][]int, count)
+		stack := make([]int, 0, count)
+		queue := make([]int, 0, count)
+
+		for source := w; source < count; source += workers {
+			for i := range count {
+				sigma[i] = 0
+				delta[i] = 0
+				dist[i] = -1
+				preds[i] = preds[i][:0]
+			}
+			sigma[source] = 1
+			dist[source] = 0
+			stack = stack[:0]
+			queue = append(queue[:0], source)
+			for head := 0; head < len(queue); head++ {
+				current := queue[head]
+				stack = append(stack, current)
+				for _, nbr := range csr.NodeNeighbors(current) {
+					if dist[nbr] == -1 {
+						dist[nbr] = dist[current] + 1
+						queue = append(queue, nbr)
+					}
+					if dist[nbr] == dist[current]+1 {
+						sigma[nbr] += sigma[current]
+						preds[nbr] = append(preds[nbr], current)
+					}
+				}
+			}
+			for idx := len(stack) - 1; idx >= 0; idx-- {
+				current := stack[idx]
+				for _, pred := range preds[current] {
+					delta[pred] += sigma[pred] / sigma[current] * (1.0 + delta[current])
+				}
+				if current != source {
+					centrality[current] += delta[current]
+				}
+			}
+		}
+		partial[w] = centrality
+	})
+
+	values := make([]float64, count)
+	for _, centrality := range partial {
+		for i, v := range centrality {
+			values[i] += v
+		}
+	}
+	//every pair was counted from both ends
+	scale := 0.5
+	if normalized && count > 2 {
+		scale = 1.0 / (float64(count-1) * float64(count-2))
+	}
+	for i := range values {
+		values[i] *= scale
+	}
+	return n.byName(values)
+}
+
+// PageRank returns the stationary distribution of a random walk that
+// follows edges in proportion to their weight, and jumps to a random
+// node with probability 1-damping or when it reaches a node without
+// out edges. Iterates until the total change falls below tolerance.
+func (n *SpatialNet) PageRank(damping, tolerance float32, maxIters int) (map[string]float32, error) {
+	count := len(n.NodeSlice)
+	if count == 0 {
+		return map[string]float32{}, nil
+	}
+	if damping < 0 || damping >= 1 {
+		return nil, errors.New("PageRank needs a damping factor in [0, 1)")
+	}
+
+	//out edges of every node, by index
+	targets, weights := n.outEdges()
+	outWeight := make([]float64, count)
+	for i := range count {
+		for _, weight := range weights[i] {
+			outWeight[i] += weight
+		}
+	}
+
+	rank := make([]float64, count)
+	next := make([]float64, count)
+	for i := range rank {
+		rank[i] = 1.0 / float64(count)
+	}
+	d := float64(damping)
+	for range maxIters {
+		dangling := 0.0
+		for i := range count {
+			if outWeight[i] <= 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1.0-d)/float64(count) + d*dangling/float64(count)
+		for i := range next {
+			next[i] = base
+		}
+		for i := range count {
+			if outWeight[i] <= 0 {
+				continue
+			}
+			for idx, j := range targets[i] {
+				next[j] += d * rank[i] * weights[i][idx] / outWeight[i]
+			}
+		}
+		change := 0.0
+		for i := range count {
+			change += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if change < float64(tolerance) {
+			return n.byName(rank), nil
+		}
+	}
+	return n.byName(rank), errors.New("PageRank did not converge within the iteration limit")
+}
+
+// EigenvectorCentrality scores each node by the weighted sum of its
+// neighbors' scores, found by power iteration on A+I so that bipartite
+// networks converge too. Scores are scaled to unit length.
+func (n *SpatialNet) EigenvectorCentrality(tolerance float32, maxIters int) (map[string]float32, error) {
+	count := len(n.NodeSlice)
+	if count == 0 {
+		return map[string]float32{}, nil
+	}
+	csr := n.CSR()
+	x := make([]float64, count)
+	next := make([]float64, count)
+	for i := range x {
+		x[i] = 1.0 / float64(count)
+	}
+	for range maxIters {
+		copy(next, x)
+		for i := range count {
+			weights := csr.NodeWeights(i)
+			for idx, j := range csr.NodeNeighbors(i) {
+				next[i] += x[j] * float64(weights[idx])
+			}
+		}
+		norm := 0.0
+		for _, v := range next {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			return n.byName(next), nil
+		}
+		change := 0.0
+		for i := range next {
+			next[i] /= norm
+			change += math.Abs(next[i] - x[i])
+		}
+		x, next = next, x
+		if change < float64(count)*float64(tolerance) {
+			return n.byName(x), nil
+		}
+	}
+	return n.byName(x), errors.New("eigenvector centrality did not converge within the iteration limit")
+}
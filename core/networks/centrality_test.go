@@ -0,0 +1,39 @@
+package networks
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBetweennessCentrality(t *testing.T) {
+	path := []weightedEdge{{"a", "b", 1.0}, {"b", "c", 1.0}, {"c", "d", 1.0}, {"d", "e", 1.0}}
+	star := []weightedEdge{{"hub", "a", 1.0}, {"hub", "b", 1.0}, {"hub", "c", 1.0}, {"hub", "d", 1.0}}
+	cycle := []weightedEdge{{"a", "b", 1.0}, {"b", "c", 1.0}, {"c", "d", 1.0}, {"d", "a", 1.0}}
+
+	tests := []struct {
+		name       string
+		edges      []weightedEdge
+		normalized bool
+		want       map[string]float32
+	}{
+		//c lies between a or b and d or e, b between a and the three past it
+		{"path", path, false, map[string]float32{"a": 0, "b": 3, "c": 4, "d": 3, "e": 0}},
+		{"path normalized", path, true, map[string]float32{"a": 0, "b": 0.5, "c": 4.0 / 6.0, "d": 0.5, "e": 0}},
+		{"star", star, false, map[string]float32{"hub": 6, "a": 0, "b": 0, "c": 0, "d": 0}},
+		{"star normalized", star, true, map[string]float32{"hub": 1, "a": 0, "b": 0, "c": 0, "d": 0}},
+		//opposite corners are joined by two paths, each taking half
+		{"cycle", cycle, false, map[string]float32{"a": 0.5, "b": 0.5, "c": 0.5, "d": 0.5}},
+	}
+	for _, test := range tests {
+		n := edgeListNet(t, test.edges)
+		for _, workers := range []uint{1, 3} {
+			got := n.BetweennessCentrality(test.normalized, workers)
+			for node, want := range test.want {
+				if math.Abs(float64(got[node]-want)) > 1e-6 {
+					t.Errorf("%s with %d workers: betweenness of %s is %v, want %v",
+						test.name, workers, node, got[node], want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package networks
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// CommunityOptions configures the community detection algorithms
+type CommunityOptions struct {
+	//Use edge weights, otherwise every edge counts as 1
+	UseWeights bool
+
+	//Resolution of the modularity, higher values give more,
+	//smaller communities
+	Resolution float64
+
+	//Seed for the order nodes are visited in
+	Seed int64
+
+	//Limit on passes over the nodes, for label propagation
+	//and for each local moving phase
+	MaxIters int
+}
+
+func NewCommunityOptions() CommunityOptions {
+	return CommunityOptions{UseWeights: true, Resolution: 1.0, MaxIters: 100}
+}
+
+// Communities is the result of a community detection. Community ids
+// run from 0 to Count-1, numbered in the order of NodeSlice.
+type Communities struct {
+	Membership map[string]int
+	Count      int
+	Modularity float64
+}
+
+// communityGraph is an undirected weighted graph indexed from 0.
+// Self loops are stored once and count twice towards strength.
+type communityGraph struct {
+	neighbors [][]int
+	weights   [][]float64
+	strength  []float64
+	total     float64 //sum of strengths, twice the total edge weight
+}
+
+func (n *SpatialNet) communityGraph(useWeights bool) *communityGraph {
+	csr := n.CSR()
+	count := len(n.NodeSlice)
+	g := &communityGraph{neighbors: make([][]int, count),
+		weights:  make([][]float64, count),
+		strength: make([]float64, count)}
+	for i := range count {
+		g.neighbors[i] = csr.NodeNeighbors(i)
+		g.weights[i] = make([]float64, len(g.neighbors[i]))
+		for idx, weight := range csr.NodeWeights(i) {
+			if useWeights {
+				g.weights[i][idx] = float64(weight)
+			} else {
+				g.weights[i][idx] = 1.0
+			}
+		}
+	}
+	g.computeStrength()
+	return g
+}
+
+func (g *communityGraph) computeStrength() {
+	g.total = 0
+	for i := range g.neighbors {
+		g.strength[i] = 0
+		for idx, j := range g.neighbors[i] {
+			g.strength[i] += g.weights[i][idx]
+			if i == j {
+				g.strength[i] += g.weights[i][idx]
+			}
+		}
+		g.total += g.strength[i]
+	}
+}
+
+// aggregate collapses every group of nodes into a single node,
+// edges inside a group become a self loop
+func (g *communityGraph) aggregate(group []int, groups int) *communityGraph {
+	linked := make([]map[int]float64, groups)
+	for c := range linked {
+		linked[c] = make(map[int]float64)
+	}
+	for i := range g.neighbors {
+		for idx, j := range g.neighbors[i] {
+			w := g.weights[i][idx]
+			if group[i] == group[j] && i != j {
+				//seen once from each end
+				w /= 2
+			}
+			linked[group[i]][group[j]] += w
+		}
+	}
+
+	agg := &communityGraph{neighbors: make([][]int, groups),
+		weights:  make([][]float64, groups),
+		strength: make([]float64, groups)}
+	for c := range groups {
+		//visit in a fixed order so results don't depend on map order
+		keys := make([]int, 0, len(linked[c]))
+		for d := range linked[c] {
+			keys = append(keys, d)
+		}
+		slices.Sort(keys)
+		for _, d := range keys {
+			agg.neighbors[c] = append(agg.neighbors[c], d)
+			agg.weights[c] = append(agg.weights[c], linked[c][d])
+		}
+	}
+	agg.computeStrength()
+	return agg
+}
+
+// modularity of a partition of g
+func (g *communityGraph) modularity(community []int, communities int, resolution float64) float64 {
+	if g.total == 0 {
+		return 0
+	}
+	internal := make([]float64, communities)
+	strength := make([]float64, communities)
+	for i := range g.neighbors {
+		strength[community[i]] += g.strength[i]
+		for idx, j := range g.neighbors[i] {
+			if community[i] == community[j] {
+				internal[community[i]] += g.weights[i][idx]
+				if i == j {
+					internal[community[i]] += g.weights[i][idx]
+				}
+			}
+		}
+	}
+	q := 0.0
+	for c := range communities {
+		q += internal[c]/g.total - resolution*(strength[c]/g.total)*(strength[c]/g.total)
+	}
+	return q
+}
+
+// renumber maps community ids to 0..k-1 in order of first appearance
+func renumber(community []int) ([]int, int) {
+	ids := make(map[int]int)
+	result := make([]int, len(community))
+	for i, c := range community {
+		id, exists := ids[c]
+		if !exists {
+			id = len(ids)
+			ids[c] = id
+		}
+		result[i] = id
+	}
+	return result, len(ids)
+}
+
+// linkWeights sums the weight of the edges from one node into each
+// community it links to. Communities are tracked as they are touched,
+// so links made only of zero weight edges still count as links.
+type linkWeights struct {
+	weight  []float64
+	linked  []bool
+	touched []int
+}
+
+func newLinkWeights(count int) *linkWeights {
+	return &linkWeights{weight: make([]float64, count), linked: make([]bool, count)}
+}
+
+func (l *linkWeights) add(c int, weight float64) {
+	if !l.linked[c] {
+		l.linked[c] = true
+		l.touched = append(l.touched, c)
+	}
+	l.weight[c] += weight
+}
+
+// reset clears the sums of every touched community
+func (l *linkWeights) reset() {
+	for _, c := range l.touched {
+		l.weight[c] = 0
+		l.linked[c] = false
+	}
+	l.touched = l.touched[:0]
+}
+
+// localMoving moves nodes of g between communities while it increases
+// modularity. Nodes are visited from a queue, and when a node moves its
+// neighbors outside its new community are queued again. Returns whether
+// any node moved.
+func (g *communityGraph) localMoving(community []int, resolution float64, rng *rand.Rand, maxIters int) bool {
+	count := len(g.neighbors)
+	tot := make([]float64, count)
+	for i := range count {
+		tot[community[i]] += g.strength[i]
+	}
+
+	queue := rng.Perm(count)
+	queued := make([]bool, count)
+	for i := range queued {
+		queued[i] = true
+	}
+	links := newLinkWeights(count)
+	moved := false
+	visits := 0
+	for head := 0; head < len(queue) && visits < maxIters*count; head++ {
+		visits++
+		i := queue[head]
+		queued[i] = false
+		current := community[i]
+
+		for idx, j := range g.neighbors[i] {
+			if i != j {
+				links.add(community[j], g.weights[i][idx])
+			}
+		}
+
+		k := g.strength[i]
+		tot[current] -= k
+		best := current
+		bestGain := links.weight[current] - resolution*tot[current]*k/g.total
+		for _, c := range links.touched {
+			gain := links.weight[c] - resolution*tot[c]*k/g.total
+			if gain > bestGain {
+				best = c
+				bestGain = gain
+			}
+		}
+		tot[best] += k
+		links.reset()
+
+		if best != current {
+			community[i] = best
+			moved = true
+			for _, j := range g.neighbors[i] {
+				if community[j] != best && !queued[j] {
+					queued[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+	return moved
+}
+
+// communitiesFromLevels follows every original node to its node in the
+// final level and from there to its community
+func (n *SpatialNet) communitiesFromLevels(membership []int, community []int, g *communityGraph, resolution float64) Communities {
+	final := make([]int, len(membership))
+	for i, node := range membership {
+		final[i] = community[node]
+	}
+	final, count := renumber(final)
+	result := Communities{Membership: make(map[string]int, len(final)), Count: count}
+	for i, c := range final {
+		result.Membership[n.NodeSlice[i].Name] = c
+	}
+	result.Modularity = g.modularity(final, count, resolution)
+	return result
+}
+
+// Louvain finds communities by moving nodes between communities while
+// modularity increases, then collapsing every community into a single
+// node and repeating (Blondel et al. 2008)
+func (n *SpatialNet) Louvain(opt CommunityOptions) Communities {
+	original := n.communityGraph(opt.UseWeights)
+	rng := rand.New(rand.NewSource(opt.Seed))
+	count := len(n.NodeSlice)
+
+	//membership maps every original node to its node in g
+	membership := make([]int, count)
+	for i := range membership {
+		membership[i] = i
+	}
+	g := original
+	community := make([]int, count)
+	for i := range community {
+		community[i] = i
+	}
+	for {
+		if !g.localMoving(community, opt.Resolution, rng, opt.MaxIters) {
+			break
+		}
+		groups, groupCount := renumber(community)
+		for i, node := range membership {
+			membership[i] = groups[node]
+		}
+		g = g.aggregate(groups, groupCount)
+		community = make([]int, groupCount)
+		for i := range community {
+			community[i] = i
+		}
+	}
+	return n.communitiesFromLevels(membership, community, original, opt.Resolution)
+}
+
+// Leiden improves on Louvain by refining every community into well
+// connected parts before collapsing, so communities can never end up
+// internally disconnected (Traag et al. 2019)
+func (n *SpatialNet) Leiden(opt CommunityOptions) Communities {
+	original := n.communityGraph(opt.UseWeights)
+	rng := rand.New(rand.NewSource(opt.Seed))
+	count := len(n.NodeSlice)
+
+	membership := make([]int, count)
+	for i := range membership {
+		membership[i] = i
+	}
+	g := original
+	community := make([]int, count)
+	for i := range community {
+		community[i] = i
+	}
+	for {
+		g.localMoving(community, opt.Resolution, rng, opt.MaxIters)
+		community, _ = renumber(community)
+		communities := 0
+		for _, c := range community {
+			communities = max(communities, c+1)
+		}
+		if communities == len(g.neighbors) {
+			break
+		}
+
+		refined, refinedCount := g.refine(community, communities, opt.Resolution, rng)
+		if refinedCount == len(g.neighbors) {
+			//nothing merged, collapse whole communities so the network shrinks
+			refined, refinedCount = community, communities
+		}
+		for i, node := range membership {
+			membership[i] = refined[node]
+		}
+		//aggregate nodes start out in the community their part came from
+		next := make([]int, refinedCount)
+		for i, part := range refined {
+			next[part] = community[i]
+		}
+		g = g.aggregate(refined, refinedCount)
+		community = next
+	}
+	return n.communitiesFromLevels(membership, community, original, opt.Resolution)
+}
+
+// refine splits every community into parts by merging singletons into
+// well connected parts of the same community. A merge is picked at random
+// with probability growing with its modularity gain.
+func (g *communityGraph) refine(community []int, communities int, resolution float64, rng *rand.Rand) ([]int, int) {
+	const randomness = 0.01
+	count := len(g.neighbors)
+
+	communityStrength := make([]float64, communities)
+	for i := range count {
+		communityStrength[community[i]] += g.strength[i]
+	}
+
+	//parts start as singletons, external is the weight from
+	//a part to the rest of its community
+	part := make([]int, count)
+	partStrength := make([]float64, count)
+	external := make([]float64, count)
+	singleton := make([]bool, count)
+	for i := range count {
+		part[i] = i
+		partStrength[i] = g.strength[i]
+		singleton[i] = true
+		for idx, j := range g.neighbors[i] {
+			if j != i && community[j] == community[i] {
+				external[i] += g.weights[i][idx]
+			}
+		}
+	}
+
+	wellConnected := func(weight, strength, total float64) bool {
+		return weight >= resolution*strength*(total-strength)/g.total
+	}
+
+	links := newLinkWeights(count)
+	var candidates []int
+	var gains []float64
+	for _, i := range rng.Perm(count) {
+		c := community[i]
+		if !singleton[i] || !wellConnected(external[i], g.strength[i], communityStrength[c]) {
+			continue
+		}
+
+		for idx, j := range g.neighbors[i] {
+			if j != i && community[j] == c {
+				links.add(part[j], g.weights[i][idx])
+			}
+		}
+
+		//staying a singleton is always a candidate with no gain
+		k := g.strength[i]
+		candidates = append(candidates[:0], part[i])
+		gains = append(gains[:0], 0.0)
+		bestGain := 0.0
+		for _, p := range links.touched {
+			if p == part[i] || !wellConnected(external[p], partStrength[p], communityStrength[c]) {
+				continue
+			}
+			gain := links.weight[p] - resolution*partStrength[p]*k/g.total
+			if gain >= 0 {
+				candidates = append(candidates, p)
+				gains = append(gains, gain)
+				bestGain = max(bestGain, gain)
+			}
+		}
+		total := 0.0
+		for idx, gain := range gains {
+			gains[idx] = math.Exp((gain - bestGain) / randomness)
+			total += gains[idx]
+		}
+		pick := rng.Float64() * total
+		chosen := candidates[len(candidates)-1]
+		for idx, chance := range gains {
+			if pick < chance {
+				chosen = candidates[idx]
+				break
+			}
+			pick -= chance
+		}
+
+		if chosen != part[i] {
+			external[chosen] += external[i] - 2*links.weight[chosen]
+			partStrength[chosen] += k
+			partStrength[part[i]] -= k
+			part[i] = chosen
+			singleton[chosen] = false
+			singleton[i] = false
+		}
+		links.reset()
+	}
+	return renumber(part)
+}
+
+// LabelPropagation finds communities by repeatedly giving each node the
+// label carrying the most edge weight among its neighbors, visiting nodes
+// in random order, until every node already has such a label
+// (Raghavan et al. 2007)
+func (n *SpatialNet) LabelPropagation(opt CommunityOptions) Communities {
+	g := n.communityGraph(opt.UseWeights)
+	rng := rand.New(rand.NewSource(opt.Seed))
+	count := len(n.NodeSlice)
+	label := make([]int, count)
+	for i := range label {
+		label[i] = i
+	}
+
+	links := newLinkWeights(count)
+	var best []int
+	for range opt.MaxIters {
+		changed := false
+		for _, i := range rng.Perm(count) {
+			for idx, j := range g.neighbors[i] {
+				if j != i {
+					links.add(label[j], g.weights[i][idx])
+				}
+			}
+			if len(links.touched) == 0 {
+				continue
+			}
+			bestWeight := math.Inf(-1)
+			for _, l := range links.touched {
+				bestWeight = max(bestWeight, links.weight[l])
+			}
+			best = best[:0]
+			for _, l := range links.touched {
+				if links.weight[l] == bestWeight {
+					best = append(best, l)
+				}
+			}
+			//keep the current label when it is one of the best
+			if !links.linked[label[i]] || links.weight[label[i]] != bestWeight {
+				label[i] = best[rng.Intn(len(best))]
+				changed = true
+			}
+			links.reset()
+		}
+		if !changed {
+			break
+		}
+	}
+
+	membership := make([]int, count)
+	for i := range membership {
+		membership[i] = i
+	}
+	return n.communitiesFromLevels(membership, label, g, opt.Resolution)
+}
+
+// Modularity returns the modularity of a partition of the network,
+// given the community of every node by name
+func (n *SpatialNet) Modularity(membership map[string]int, useWeights bool, resolution float64) float64 {
+	g := n.communityGraph(useWeights)
+	community := make([]int, len(n.NodeSlice))
+	for i, node := range n.NodeSlice {
+		community[i] = membership[node.Name]
+	}
+	community, count := renumber(community)
+	return g.modularity(community, count, resolution)
+}
+
+// communityPalette holds distinct colors, communities
+// past the end of the palette reuse them
+var communityPalette = []Color{{31, 119, 180, 255},
+	{255, 127, 14, 255},
+	{44, 160, 44, 255},
+	{214, 39, 40, 255},
+	{148, 103, 189, 255},
+	{140, 86, 75, 255},
+	{227, 119, 194, 255},
+	{127, 127, 127, 255},
+	{188, 189, 34, 255},
+	{23, 190, 207, 255}}
+
+// StoreCommunities saves the community of every node as the int node
+// attribute name, and colours nodes by community through the "color"
+// attribute so the renderers pick it up
+func (n *SpatialNet) StoreCommunities(name string, communities Communities) error {
+	err := n.NodeAttributes.Define(name, AttributeInt)
+	if err != nil {
+		return err
+	}
+	err = n.NodeAttributes.Define("color", AttributeColor)
+	if err != nil {
+		return err
+	}
+	for node, c := range communities.Membership {
+		if !n.ContainsNode(node) {
+			continue
+		}
+		n.NodeAttributes.SetInt(node, name, c)
+		n.NodeAttributes.SetColor(node, "color", communityPalette[c%len(communityPalette)])
+	}
+	return nil
+}
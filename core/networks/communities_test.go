@@ -0,0 +1,146 @@
+package networks
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// edgeListNet builds an undirected network from weighted edges,
+// adding nodes as they first appear
+func edgeListNet(t *testing.T, edges []weightedEdge) *SpatialNet {
+	t.Helper()
+	n := NewSpatialNet()
+	for _, e := range edges {
+		for _, name := range []string{e.a, e.b} {
+			if !n.ContainsNode(name) {
+				n.AddNode(name)
+			}
+		}
+		err := n.AddWeightedEdge(e.a, e.b, e.weight)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return n
+}
+
+type weightedEdge struct {
+	a, b   string
+	weight float32
+}
+
+// cliqueEdges returns the edges of a clique on the named nodes
+func cliqueEdges(names ...string) []weightedEdge {
+	var edges []weightedEdge
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			edges = append(edges, weightedEdge{names[i], names[j], 1.0})
+		}
+	}
+	return edges
+}
+
+// twoCliques returns two cliques of size nodes joined by a single edge
+func twoCliques(size int) ([]weightedEdge, []string, []string) {
+	var left, right []string
+	for i := range size {
+		left = append(left, "a"+strconv.Itoa(i))
+		right = append(right, "b"+strconv.Itoa(i))
+	}
+	edges := append(cliqueEdges(left...), cliqueEdges(right...)...)
+	edges = append(edges, weightedEdge{left[0], right[0], 1.0})
+	return edges, left, right
+}
+
+func TestCommunitiesTwoCliques(t *testing.T) {
+	edges, left, right := twoCliques(5)
+	algorithms := map[string]func(*SpatialNet, CommunityOptions) Communities{
+		"louvain":          (*SpatialNet).Louvain,
+		"leiden":           (*SpatialNet).Leiden,
+		"labelPropagation": (*SpatialNet).LabelPropagation,
+	}
+	for name, detect := range algorithms {
+		for seed := range int64(5) {
+			n := edgeListNet(t, edges)
+			opt := NewCommunityOptions()
+			opt.Seed = seed
+			result := detect(n, opt)
+			if result.Count != 2 {
+				t.Fatalf("%s seed %d: found %d communities, want 2", name, seed, result.Count)
+			}
+			for _, group := range [][]string{left, right} {
+				for _, node := range group {
+					if result.Membership[node] != result.Membership[group[0]] {
+						t.Errorf("%s seed %d: %s is not with %s", name, seed, node, group[0])
+					}
+				}
+			}
+			if result.Membership[left[0]] == result.Membership[right[0]] {
+				t.Errorf("%s seed %d: the cliques share a community", name, seed)
+			}
+			want := n.Modularity(result.Membership, true, 1.0)
+			if math.Abs(result.Modularity-want) > 1e-9 {
+				t.Errorf("%s seed %d: modularity %v, Modularity gives %v", name, seed, result.Modularity, want)
+			}
+		}
+	}
+}
+
+func TestModularity(t *testing.T) {
+	//two triangles joined by the edge c-d
+	triangles := append(cliqueEdges("a", "b", "c"), cliqueEdges("d", "e", "f")...)
+	split := map[string]int{"a": 0, "b": 0, "c": 0, "d": 1, "e": 1, "f": 1}
+	together := map[string]int{"a": 0, "b": 0, "c": 0, "d": 0, "e": 0, "f": 0}
+
+	tests := []struct {
+		name       string
+		bridge     float32
+		membership map[string]int
+		useWeights bool
+		resolution float64
+		want       float64
+	}{
+		//7 edges, each triangle holds 3 of them and degrees summing to 7
+		{"split", 1.0, split, true, 1.0, 2 * (3.0/7.0 - 0.25)},
+		{"one community", 1.0, together, true, 1.0, 0.0},
+		{"resolution", 1.0, split, true, 2.0, 2 * (3.0/7.0 - 2*0.25)},
+		//a bridge of weight 3 gives a total weight of 9
+		{"weighted bridge", 3.0, split, true, 1.0, 2 * (3.0/9.0 - 0.25)},
+		{"weights ignored", 3.0, split, false, 1.0, 2 * (3.0/7.0 - 0.25)},
+	}
+	for _, test := range tests {
+		edges := append(slices.Clone(triangles), weightedEdge{"c", "d", test.bridge})
+		n := edgeListNet(t, edges)
+		got := n.Modularity(test.membership, test.useWeights, test.resolution)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: modularity %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestModularityWithoutEdges(t *testing.T) {
+	n := NewSpatialNet()
+	n.AddNode("a")
+	n.AddNode("b")
+	got := n.Modularity(map[string]int{"a": 0, "b": 1}, true, 1.0)
+	if got != 0.0 {
+		t.Errorf("modularity %v, want 0", got)
+	}
+}
+
+func TestLabelPropagationZeroWeightEdges(t *testing.T) {
+	//x only links to the triangle through edges of weight 0
+	edges := append(cliqueEdges("a", "b", "c"),
+		weightedEdge{"x", "a", 0.0}, weightedEdge{"x", "b", 0.0})
+	for seed := range int64(5) {
+		n := edgeListNet(t, edges)
+		opt := NewCommunityOptions()
+		opt.Seed = seed
+		result := n.LabelPropagation(opt)
+		if result.Membership["x"] != result.Membership["a"] {
+			t.Errorf("seed %d: x kept a label none of its neighbors have", seed)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package networks
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ComponentLabels returns the connected component of every node, indexed
+// by position in NodeSlice, and the number of components. Edges of
+// directed networks are followed both ways, giving weak components.
+// Components are numbered from the largest down.
+func (n *SpatialNet) ComponentLabels() ([]int, int) {
+	components := n.ConnectedComponents()
+	labels := make([]int, len(n.NodeSlice))
+	for c, members := range components {
+		for _, i := range members {
+			labels[i] = c
+		}
+	}
+	return labels, len(components)
+}
+
+// ConnectedComponents returns the indeces of the nodes in every connected
+// component, largest first. Ties keep the order of NodeSlice.
+func (n *SpatialNet) ConnectedComponents() [][]int {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+	seen := make([]bool, count)
+	var components [][]int
+	for start := range count {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		members := []int{start}
+		for head := 0; head < len(members); head++ {
+			for _, nbr := range csr.NodeNeighbors(members[head]) {
+				if !seen[nbr] {
+					seen[nbr] = true
+					members = append(members, nbr)
+				}
+			}
+		}
+		sort.Ints(members)
+		components = append(components, members)
+	}
+	sort.SliceStable(components, func(a, b int) bool {
+		return len(components[a]) > len(components[b])
+	})
+	return components
+}
+
+// componentBounds returns the bounding box of a set of nodes,
+// including their radii
+func (n *SpatialNet) componentBounds(members []int) (minX, minY, maxX, maxY float32) {
+	minX, minY = float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY = float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, i := range members {
+		node := &n.NodeSlice[i]
+		minX = min(minX, node.X-node.Radius)
+		minY = min(minY, node.Y-node.Radius)
+		maxX = max(maxX, node.X+node.Radius)
+		maxY = max(maxY, node.Y+node.Radius)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// PackComponents moves every connected component as a whole so their
+// bounding boxes fill a roughly square rectangle centered on the origin,
+// with padding between them. Boxes are placed tallest first along shelves.
+// Components holding a pinned or constrained node stay where they are,
+// and the others are packed to their right.
+func (n *SpatialNet) PackComponents(padding float32) {
+	components := n.ConnectedComponents()
+	n.packComponents(components, n.planShelves(components, padding), padding)
+}
+
+// componentBox is the bounding box of a component grown by the padding
+type componentBox struct {
+	minX, minY, w, h float32
+}
+
+func (n *SpatialNet) componentBoxes(components [][]int, padding float32) []componentBox {
+	boxes := make([]componentBox, len(components))
+	for c, members := range components {
+		minX, minY, maxX, maxY := n.componentBounds(members)
+		boxes[c] = componentBox{minX: minX, minY: minY,
+			w: maxX - minX + padding, h: maxY - minY + padding}
+	}
+	return boxes
+}
+
+// anchored reports whether any of the nodes is pinned or constrained,
+// so moving them as a whole would break their constraints
+func (n *SpatialNet) anchored(members []int) bool {
+	for _, i := range members {
+		if n.NodeSlice[i].Pinned || n.NodeSlice[i].Constraint != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// planShelves splits the components that are free to move into shelves,
+// rows of components placed left to right, filling shelves tallest first
+// up to a width that makes the packing roughly square
+func (n *SpatialNet) planShelves(components [][]int, padding float32) [][]int {
+	boxes := n.componentBoxes(components, padding)
+	var area float64
+	var widest float32
+	var order []int
+	for c, b := range boxes {
+		if n.anchored(components[c]) {
+			continue
+		}
+		area += float64(b.w) * float64(b.h)
+		widest = max(widest, b.w)
+		order = append(order, c)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return boxes[order[a]].h > boxes[order[b]].h
+	})
+
+	shelfWidth := max(widest, float32(math.Sqrt(area)))
+	var shelves [][]int
+	var x float32
+	for _, c := range order {
+		if len(shelves) == 0 || (x > 0 && x+boxes[c].w > shelfWidth) {
+			shelves = append(shelves, nil)
+			x = 0
+		}
+		shelves[len(shelves)-1] = append(shelves[len(shelves)-1], c)
+		x += boxes[c].w
+	}
+	return shelves
+}
+
+// packComponents moves the components onto the shelves from planShelves,
+// sized by their current bounding boxes. Components left off the shelves
+// don't move, and the shelves are placed to their right, or centered on
+// the origin if there are none. Keeping the shelves of an earlier plan
+// lets components grow and shrink without trading places.
+func (n *SpatialNet) packComponents(components [][]int, shelves [][]int, padding float32) {
+	boxes := n.componentBoxes(components, padding)
+	offsets := make([][2]float32, len(components))
+	shelved := make([]bool, len(components))
+	var y, totalWidth float32
+	for _, shelf := range shelves {
+		var x, shelfHeight float32
+		for _, c := range shelf {
+			offsets[c] = [2]float32{x - boxes[c].minX, y - boxes[c].minY}
+			shelved[c] = true
+			x += boxes[c].w
+			shelfHeight = max(shelfHeight, boxes[c].h)
+		}
+		totalWidth = max(totalWidth, x)
+		y += shelfHeight
+	}
+	totalHeight := y
+
+	//center the packing on the origin, or beside the components that stay
+	originX, originY := -totalWidth/2, -totalHeight/2
+	var fixed []int
+	for c, members := range components {
+		if !shelved[c] {
+			fixed = append(fixed, members...)
+		}
+	}
+	if len(fixed) > 0 {
+		_, minY, maxX, maxY := n.componentBounds(fixed)
+		originX = maxX + padding
+		originY = (minY+maxY)/2 - totalHeight/2
+	}
+	for c, members := range components {
+		if !shelved[c] {
+			continue
+		}
+		dx := offsets[c][0] + originX
+		dy := offsets[c][1] + originY
+		for _, i := range members {
+			n.NodeSlice[i].move(dx, dy)
+		}
+	}
+}
+
+// componentNet copies the nodes in members and the edges between them
+// into a new network, keeping positions and edge weights
+func (n *SpatialNet) componentNet(members []int) *SpatialNet {
+	var c *SpatialNet
+	if n.Directed {
+		c = NewDirectedSpatialNet()
+	} else {
+		c = NewSpatialNet()
+	}
+	c.Seed(n.random().Int63())
+	for _, i := range members {
+		node := n.NodeSlice[i]
+		c.AddNode(node.Name)
+		c.NodeSlice[len(c.NodeSlice)-1] = node
+	}
+	for _, i := range members {
+		name := n.NodeSlice[i].Name
+		for nbr, weight := range n.Adjacencies[name] {
+			if c.ContainsNode(nbr) {
+				c.AddWeightedEdge(name, nbr, weight)
+			}
+		}
+	}
+	return c
+}
+
+// PackedLayout lays out every connected component on its own with an
+// inner layout, then packs the components like PackComponents so they
+// don't drift apart and leave the view mostly empty. Shelves are planned
+// once in Init, steps only resize them, so components never jump.
+type PackedLayout struct {
+	//Name of the registered layout run on every component
+	InnerLayout string
+
+	//Space left between the bounding boxes of components
+	Padding float32
+
+	//holds the parameters of the inner layout
+	inner Layout
+
+	components [][]int
+	shelves    [][]int
+	nets       []*SpatialNet
+	layouts    []Layout
+	csr        *CSR
+}
+
+func NewPackedLayout(inner string) (*PackedLayout, error) {
+	layout, err := NewLayout(inner)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedLayout{InnerLayout: inner, Padding: 20.0, inner: layout}, nil
+}
+
+func (p *PackedLayout) Init(n *SpatialNet) error {
+	if p.inner == nil {
+		return errors.New("packed layout has no inner layout")
+	}
+	p.csr = nil
+	p.components = n.ConnectedComponents()
+	p.nets = make([]*SpatialNet, len(p.components))
+	p.layouts = make([]Layout, len(p.components))
+	for c, members := range p.components {
+		p.nets[c] = n.componentNet(members)
+		layout, err := NewLayout(p.InnerLayout)
+		if err != nil {
+			return err
+		}
+		for param, value := range p.inner.Parameters() {
+			layout.SetParameter(param, value)
+		}
+		err = layout.Init(p.nets[c])
+		if err != nil {
+			return err
+		}
+		p.layouts[c] = layout
+	}
+	p.csr = n.CSR()
+	p.copyBack(n)
+	p.shelves = n.planShelves(p.components, p.Padding)
+	n.packComponents(p.components, p.shelves, p.Padding)
+	return nil
+}
+
+// copyBack moves the positions from the component networks into n
+func (p *PackedLayout) copyBack(n *SpatialNet) {
+	for c, members := range p.components {
+		for idx, i := range members {
+			node := &p.nets[c].NodeSlice[idx]
+			n.NodeSlice[i].X = node.X
+			n.NodeSlice[i].Y = node.Y
+			n.NodeSlice[i].Vx = node.Vx
+			n.NodeSlice[i].Vy = node.Vy
+		}
+	}
+}
+
+func (p *PackedLayout) Step(n *SpatialNet) {
+	if p.csr != n.CSR() || len(p.nets) != len(p.components) {
+		if p.Init(n) != nil {
+			return
+		}
+	}
+	for c := range p.nets {
+		if !p.layouts[c].Converged() {
+			p.layouts[c].Step(p.nets[c])
+		}
+	}
+	p.copyBack(n)
+	n.packComponents(p.components, p.shelves, p.Padding)
+}
+
+func (p *PackedLayout) Converged() bool {
+	//a network without components has nothing to lay out,
+	//but only once Init has run without an error
+	if p.csr == nil {
+		return false
+	}
+	for _, layout := range p.layouts {
+		if !layout.Converged() {
+			return false
+		}
+	}
+	return true
+}
+
+// Parameters returns the padding along with the parameters of the inner layout
+func (p *PackedLayout) Parameters() map[string]float32 {
+	params := make(map[string]float32)
+	if p.inner != nil {
+		params = p.inner.Parameters()
+	}
+	params["padding"] = p.Padding
+	return params
+}
+
+func (p *PackedLayout) SetParameter(name string, value float32) error {
+	if name == "padding" {
+		p.Padding = value
+		return nil
+	}
+	if p.inner == nil {
+		return unknownParameter(name)
+	}
+	err := p.inner.SetParameter(name, value)
+	if err != nil {
+		return err
+	}
+	for _, layout := range p.layouts {
+		layout.SetParameter(name, value)
+	}
+	return nil
+}
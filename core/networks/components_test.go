@@ -0,0 +1,190 @@
+package networks
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// componentsNet returns a network with cliques of the given sizes,
+// scattered around the origin
+func componentsNet(t *testing.T, sizes ...int) *SpatialNet {
+	t.Helper()
+	n := NewSpatialNet()
+	n.Seed(1)
+	for c, size := range sizes {
+		var names []string
+		for i := range size {
+			name := strconv.Itoa(c) + "-" + strconv.Itoa(i)
+			n.AddNode(name)
+			names = append(names, name)
+		}
+		for _, e := range cliqueEdges(names...) {
+			if err := n.AddEdge(e.a, e.b); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i := range n.NodeSlice {
+		n.NodeSlice[i].X = n.Rng.Float32()*200 - 100
+		n.NodeSlice[i].Y = n.Rng.Float32()*200 - 100
+	}
+	return n
+}
+
+// checkShelves checks that the components sit in the slots of the
+// planned shelves: side by side along every shelf, padding apart, with
+// each shelf below the tallest box of the one before it
+func checkShelves(t *testing.T, n *SpatialNet, components, shelves [][]int, padding float32) {
+	t.Helper()
+	boxes := n.componentBoxes(components, padding)
+	near := func(a, b float32) bool {
+		return math.Abs(float64(a-b)) <= 1e-3*max(1.0, math.Abs(float64(b)))
+	}
+	for s, shelf := range shelves {
+		var shelfHeight float32
+		for idx, c := range shelf {
+			shelfHeight = max(shelfHeight, boxes[c].h)
+			if idx == 0 {
+				continue
+			}
+			prev := boxes[shelf[idx-1]]
+			if !near(boxes[c].minX, prev.minX+prev.w) || !near(boxes[c].minY, prev.minY) {
+				t.Fatalf("shelf %d: component %d at (%v, %v), want (%v, %v)",
+					s, c, boxes[c].minX, boxes[c].minY, prev.minX+prev.w, prev.minY)
+			}
+		}
+		if s+1 < len(shelves) {
+			next := boxes[shelves[s+1][0]]
+			if !near(next.minY, boxes[shelf[0]].minY+shelfHeight) {
+				t.Fatalf("shelf %d starts at y %v, want %v", s+1, next.minY, boxes[shelf[0]].minY+shelfHeight)
+			}
+		}
+	}
+}
+
+func TestPackedLayoutKeepsComponentSlots(t *testing.T) {
+	n := componentsNet(t, 6, 4, 3, 3, 2, 1, 1)
+	p, err := NewPackedLayout("spring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(n); err != nil {
+		t.Fatal(err)
+	}
+	shelves := make([][]int, len(p.shelves))
+	for s := range p.shelves {
+		shelves[s] = slices.Clone(p.shelves[s])
+	}
+	if len(shelves) < 2 {
+		t.Fatalf("planned %d shelves, want several", len(shelves))
+	}
+	checkShelves(t, n, p.components, shelves, p.Padding)
+
+	//components grow and shrink as they settle, but stay in their slots
+	for step := range 50 {
+		p.Step(n)
+		for s := range shelves {
+			if !slices.Equal(p.shelves[s], shelves[s]) {
+				t.Fatalf("step %d: shelf %d holds %v, want %v", step, s, p.shelves[s], shelves[s])
+			}
+		}
+		checkShelves(t, n, p.components, shelves, p.Padding)
+	}
+}
+
+func TestPackedLayoutEmptyNetworkConverges(t *testing.T) {
+	p, err := NewPackedLayout("spring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Converged() {
+		t.Error("converged before Init")
+	}
+	n := NewSpatialNet()
+	if err := p.Init(n); err != nil {
+		t.Fatal(err)
+	}
+	p.Step(n)
+	if !p.Converged() {
+		t.Error("a network without components never converges")
+	}
+}
+
+func TestPackingLeavesAnchoredComponents(t *testing.T) {
+	const padding = 20.0
+	anchors := map[string]func(n *SpatialNet) error{
+		"pinned": func(n *SpatialNet) error { return n.Pin("1-0", 300.0, -40.0) },
+		"constrained": func(n *SpatialNet) error {
+			return n.SetConstraint("1-0", FixedPoint(300.0, -40.0))
+		},
+	}
+	packers := map[string]func(t *testing.T, n *SpatialNet){
+		"PackComponents": func(t *testing.T, n *SpatialNet) { n.PackComponents(padding) },
+		"PackedLayout": func(t *testing.T, n *SpatialNet) {
+			p, err := NewPackedLayout("spring")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Padding = padding
+			if err := p.Init(n); err != nil {
+				t.Fatal(err)
+			}
+			for range 20 {
+				p.Step(n)
+			}
+		},
+	}
+	for anchorName, anchor := range anchors {
+		for packerName, pack := range packers {
+			n := componentsNet(t, 5, 3, 3, 2, 1)
+			if err := anchor(n); err != nil {
+				t.Fatal(err)
+			}
+			//clamp the constrained node onto its point before packing
+			n.NodeSlice[n.NodeIndeces["1-0"]].constrain()
+			before := map[string][2]float32{}
+			for _, node := range n.NodeSlice {
+				before[node.Name] = [2]float32{node.X, node.Y}
+			}
+			pack(t, n)
+
+			var anchored []int
+			for _, members := range n.ConnectedComponents() {
+				if n.anchored(members) {
+					anchored = members
+				}
+			}
+			if len(anchored) != 3 {
+				t.Fatalf("%s %s: anchored component has %d nodes, want 3", anchorName, packerName, len(anchored))
+			}
+			held := n.NodeSlice[n.NodeIndeces["1-0"]]
+			if held.X != 300.0 || held.Y != -40.0 {
+				t.Errorf("%s %s: the anchor moved to (%v, %v)", anchorName, packerName, held.X, held.Y)
+			}
+			//PackComponents doesn't move the component at all, a packed
+			//layout still lays it out but never shifts it as a whole
+			if packerName == "PackComponents" {
+				for _, i := range anchored {
+					node := n.NodeSlice[i]
+					if before[node.Name] != [2]float32{node.X, node.Y} {
+						t.Errorf("%s %s: %s moved", anchorName, packerName, node.Name)
+					}
+				}
+			}
+
+			_, _, maxX, _ := n.componentBounds(anchored)
+			for i := range n.NodeSlice {
+				if slices.Contains(anchored, i) {
+					continue
+				}
+				node := n.NodeSlice[i]
+				if node.X-node.Radius < maxX+padding-1e-3 {
+					t.Errorf("%s %s: %s at x %v is not right of the anchored component ending at %v",
+						anchorName, packerName, node.Name, node.X, maxX)
+				}
+			}
+		}
+	}
+}
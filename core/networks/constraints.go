@@ -0,0 +1,112 @@
+package networks
+
+import "errors"
+
+// Constraint limits where layouts may place a node. A node with both
+// FixX and FixY is held at (X, Y), with only one it slides along a line.
+// Constraints are applied after every move, so they win over forces.
+type Constraint struct {
+	FixX, FixY bool
+	X, Y       float32
+
+	//Keep the node inside [MinX, MaxX] by [MinY, MaxY]
+	Bounded                bool
+	MinX, MinY, MaxX, MaxY float32
+}
+
+func FixedPoint(x, y float32) *Constraint {
+	return &Constraint{FixX: true, FixY: true, X: x, Y: y}
+}
+
+func FixedX(x float32) *Constraint {
+	return &Constraint{FixX: true, X: x}
+}
+
+func FixedY(y float32) *Constraint {
+	return &Constraint{FixY: true, Y: y}
+}
+
+func BoundingRegion(minX, minY, maxX, maxY float32) *Constraint {
+	return &Constraint{Bounded: true, MinX: minX, MinY: minY, MaxX: maxX, MaxY: maxY}
+}
+
+// constrain moves the node back inside its constraint, stopping
+// its velocity along any axis the constraint held it on
+func (snn *SpatialNetNode) constrain() {
+	c := snn.Constraint
+	if c == nil {
+		return
+	}
+	x, y := snn.X, snn.Y
+	if c.Bounded {
+		x = min(c.MaxX, max(c.MinX, x))
+		y = min(c.MaxY, max(c.MinY, y))
+	}
+	if c.FixX {
+		x = c.X
+	}
+	if c.FixY {
+		y = c.Y
+	}
+	if x != snn.X {
+		snn.X = x
+		snn.Vx = 0.0
+	}
+	if y != snn.Y {
+		snn.Y = y
+		snn.Vy = 0.0
+	}
+}
+
+// move shifts the node by (dx, dy) within its constraint.
+// Pinned nodes stay put and lose their velocity.
+func (snn *SpatialNetNode) move(dx, dy float32) {
+	if snn.Pinned {
+		snn.Vx = 0.0
+		snn.Vy = 0.0
+		return
+	}
+	snn.X += dx
+	snn.Y += dy
+	snn.constrain()
+}
+
+// Pin places a node at (x, y) and keeps it there during layouts
+func (n *SpatialNet) Pin(name string, x, y float32) error {
+	i, exists := n.NodeIndeces[name]
+	if !exists {
+		return errors.New("node " + name + " does not exist")
+	}
+	node := &n.NodeSlice[i]
+	node.X = x
+	node.Y = y
+	node.Vx = 0.0
+	node.Vy = 0.0
+	node.Pinned = true
+	return nil
+}
+
+// Unpin lets layouts move a node again
+func (n *SpatialNet) Unpin(name string) error {
+	i, exists := n.NodeIndeces[name]
+	if !exists {
+		return errors.New("node " + name + " does not exist")
+	}
+	n.NodeSlice[i].Pinned = false
+	return nil
+}
+
+// SetConstraint limits where layouts may place a node, nil removes
+// the limit. The node is moved inside the constraint right away.
+func (n *SpatialNet) SetConstraint(name string, c *Constraint) error {
+	i, exists := n.NodeIndeces[name]
+	if !exists {
+		return errors.New("node " + name + " does not exist")
+	}
+	if c != nil && c.Bounded && (c.MinX > c.MaxX || c.MinY > c.MaxY) {
+		return errors.New("bounding region of " + name + " is empty")
+	}
+	n.NodeSlice[i].Constraint = c
+	n.NodeSlice[i].constrain()
+	return nil
+}
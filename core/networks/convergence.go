@@ -0,0 +1,132 @@
+package networks
+
+import (
+	"math"
+	"sync"
+)
+
+// StepStats describes how far a layout step moved the nodes.
+// Energy is the kinetic energy of the step, the sum over nodes of
+// half their squared displacement, treating every node as unit mass.
+type StepStats struct {
+	Iteration       int
+	Energy          float64
+	MaxDisplacement float32
+}
+
+// StagedLayout is implemented by layouts that move another network than
+// the one passed to Step, such as MultilevelLayout refining its coarse
+// levels. Convergence is measured on the network ActiveNetwork returns.
+type StagedLayout interface {
+	ActiveNetwork(n *SpatialNet) *SpatialNet
+}
+
+// ConvergenceMonitor steps a layout while recording the energy of every
+// step. The layout counts as converged once the maximum displacement
+// stays under Tolerance, and the energy under EnergyTolerance, for
+// Patience consecutive steps, or once the layout itself says so.
+type ConvergenceMonitor struct {
+	//Largest displacement of any node for a step to count as settled,
+	//0 never settles
+	Tolerance float32
+
+	//Largest energy for a step to count as settled, 0 ignores energy
+	EnergyTolerance float64
+
+	//Consecutive settled steps needed to converge
+	Patience int
+
+	mu              sync.Mutex
+	trace           []StepStats
+	settled         int
+	layoutConverged bool
+	prevX, prevY    []float32
+}
+
+func NewConvergenceMonitor(tolerance float32, patience int) *ConvergenceMonitor {
+	return &ConvergenceMonitor{Tolerance: tolerance, Patience: max(patience, 1)}
+}
+
+// activeNetwork returns the network layout is actually moving
+func activeNetwork(layout Layout, n *SpatialNet) *SpatialNet {
+	staged, isType := layout.(StagedLayout)
+	if isType {
+		return staged.ActiveNetwork(n)
+	}
+	return n
+}
+
+// Step runs one step of layout on n and records its stats
+func (c *ConvergenceMonitor) Step(layout Layout, n *SpatialNet) StepStats {
+	before := activeNetwork(layout, n)
+	c.prevX = c.prevX[:0]
+	c.prevY = c.prevY[:0]
+	for _, node := range before.NodeSlice {
+		c.prevX = append(c.prevX, node.X)
+		c.prevY = append(c.prevY, node.Y)
+	}
+
+	layout.Step(n)
+
+	after := activeNetwork(layout, n)
+	stats := StepStats{}
+	measured := after == before && len(after.NodeSlice) == len(c.prevX)
+	if measured {
+		for i, node := range after.NodeSlice {
+			dx := float64(node.X - c.prevX[i])
+			dy := float64(node.Y - c.prevY[i])
+			stats.Energy += 0.5 * (dx*dx + dy*dy)
+			stats.MaxDisplacement = max(stats.MaxDisplacement, float32(math.Hypot(dx, dy)))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.Iteration = len(c.trace)
+	c.trace = append(c.trace, stats)
+	//steps that switch networks or nodes don't count towards settling
+	if measured && stats.MaxDisplacement < c.Tolerance &&
+		(c.EnergyTolerance == 0 || stats.Energy < c.EnergyTolerance) {
+		c.settled++
+	} else {
+		c.settled = 0
+	}
+	c.layoutConverged = layout.Converged()
+	return stats
+}
+
+// Converged reports whether the layout has settled
+func (c *ConvergenceMonitor) Converged() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.layoutConverged || c.settled >= c.Patience
+}
+
+// Trace returns a copy of the stats of every step so far,
+// safe to call while another go routine is stepping
+func (c *ConvergenceMonitor) Trace() []StepStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	trace := make([]StepStats, len(c.trace))
+	copy(trace, c.trace)
+	return trace
+}
+
+// Last returns the stats of the latest step, and false before the first
+func (c *ConvergenceMonitor) Last() (StepStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.trace) == 0 {
+		return StepStats{}, false
+	}
+	return c.trace[len(c.trace)-1], true
+}
+
+// Reset clears the trace so the monitor can follow a new run
+func (c *ConvergenceMonitor) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trace = nil
+	c.settled = 0
+	c.layoutConverged = false
+}
@@ -0,0 +1,94 @@
+package networks
+
+import (
+	"math"
+	"testing"
+)
+
+// scriptedLayout moves the first node right by the next
+// distance on every step, and converges when told to
+type scriptedLayout struct {
+	moves     []float32
+	step      int
+	converged bool
+}
+
+func (s *scriptedLayout) Init(n *SpatialNet) error { return nil }
+func (s *scriptedLayout) Step(n *SpatialNet) {
+	n.NodeSlice[0].X += s.moves[s.step]
+	s.step++
+}
+func (s *scriptedLayout) Converged() bool                               { return s.converged }
+func (s *scriptedLayout) Parameters() map[string]float32                { return nil }
+func (s *scriptedLayout) SetParameter(name string, value float32) error { return nil }
+
+func scriptedNet() *SpatialNet {
+	n := NewSpatialNet()
+	n.AddNode("a")
+	n.AddNode("b")
+	return n
+}
+
+func TestConvergenceMonitorPatience(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance float32
+		patience  int
+		moves     []float32
+		want      []bool
+	}{
+		{"settles", 1.0, 2, []float32{5, 0.5, 0.5, 0.5}, []bool{false, false, true, true}},
+		{"large step resets", 1.0, 2, []float32{0.5, 5, 0.5, 0.5}, []bool{false, false, false, true}},
+		{"zero tolerance never settles", 0.0, 1, []float32{0, 0, 0}, []bool{false, false, false}},
+	}
+	for _, test := range tests {
+		n := scriptedNet()
+		layout := &scriptedLayout{moves: test.moves}
+		monitor := NewConvergenceMonitor(test.tolerance, test.patience)
+		for step, want := range test.want {
+			monitor.Step(layout, n)
+			if monitor.Converged() != want {
+				t.Errorf("%s: converged %v after step %d, want %v", test.name, !want, step, want)
+			}
+		}
+	}
+}
+
+func TestConvergenceMonitorEnergy(t *testing.T) {
+	n := scriptedNet()
+	layout := &scriptedLayout{moves: []float32{0.5, 0.1}}
+	monitor := NewConvergenceMonitor(1.0, 1)
+	//a displacement of 0.5 has energy 0.125
+	monitor.EnergyTolerance = 0.1
+	monitor.Step(layout, n)
+	if monitor.Converged() {
+		t.Error("converged with energy over the tolerance")
+	}
+	stats := monitor.Step(layout, n)
+	if !monitor.Converged() {
+		t.Error("did not converge with energy under the tolerance")
+	}
+	if stats.Iteration != 1 || math.Abs(stats.Energy-0.005) > 1e-6 ||
+		math.Abs(float64(stats.MaxDisplacement)-0.1) > 1e-6 {
+		t.Errorf("step stats %+v, want iteration 1, energy 0.005 and displacement 0.1", stats)
+	}
+}
+
+func TestConvergenceMonitorLayoutConverged(t *testing.T) {
+	n := scriptedNet()
+	layout := &scriptedLayout{moves: []float32{5, 5}}
+	monitor := NewConvergenceMonitor(0.0, 1)
+	monitor.Step(layout, n)
+	layout.converged = true
+	monitor.Step(layout, n)
+	if !monitor.Converged() {
+		t.Error("a converged layout was not reported as converged")
+	}
+	if len(monitor.Trace()) != 2 {
+		t.Errorf("trace has %d steps, want 2", len(monitor.Trace()))
+	}
+	monitor.Reset()
+	if _, stepped := monitor.Last(); stepped || monitor.Converged() {
+		t.Error("reset kept the last run")
+	}
+}
@@ -0,0 +1,89 @@
+package networks
+
+import (
+	"slices"
+)
+
+// CSR is a compressed sparse row view of the adjacencies of a SpatialNet,
+// indexed by position in NodeSlice. The neighbors of node i are
+// Neighbors[Offsets[i]:Offsets[i+1]], sorted in ascending order.
+// Edges of directed networks are included in both directions, since
+// layouts pull neighbors together regardless of direction.
+// Weights holds the weight of each edge in Neighbors, nodes linked in
+// both directions of a directed network get the sum of both weights.
+type CSR struct {
+	Offsets   []int
+	Neighbors []int
+	Weights   []float32
+}
+
+// NodeNeighbors returns the indeces of the neighbors of node i
+func (c *CSR) NodeNeighbors(i int) []int {
+	return c.Neighbors[c.Offsets[i]:c.Offsets[i+1]]
+}
+
+// NodeWeights returns the weights of the edges to the neighbors of node i
+func (c *CSR) NodeWeights(i int) []float32 {
+	return c.Weights[c.Offsets[i]:c.Offsets[i+1]]
+}
+
+// Degree returns the number of neighbors of node i
+func (c *CSR) Degree(i int) int {
+	return c.Offsets[i+1] - c.Offsets[i]
+}
+
+// HasEdge reports whether node j is a neighbor of node i
+func (c *CSR) HasEdge(i, j int) bool {
+	_, found := slices.BinarySearch(c.NodeNeighbors(i), j)
+	return found
+}
+
+// CSR returns the index based view of Adjacencies, rebuilding it if
+// nodes or edges were added since it was last built. Code that edits
+// Adjacencies directly instead of using AddNode and AddEdge must call
+// InvalidateCSR afterwards.
+// The view is shared, so it must not be modified and should be fetched
+// once before starting any go routines.
+func (n *SpatialNet) CSR() *CSR {
+	if n.csr != nil && len(n.csr.Offsets) == len(n.NodeSlice)+1 {
+		return n.csr
+	}
+
+	type csrEntry struct {
+		neighbor int
+		weight   float32
+	}
+
+	c := &CSR{Offsets: make([]int, len(n.NodeSlice)+1)}
+	var row []csrEntry
+	for i, node := range n.NodeSlice {
+		row = row[:0]
+		for nbr, weight := range n.Adjacencies[node.Name] {
+			row = append(row, csrEntry{int(n.NodeIndeces[nbr]), weight})
+		}
+		if n.Directed {
+			for nbr, weight := range n.InAdjacencies[node.Name] {
+				row = append(row, csrEntry{int(n.NodeIndeces[nbr]), weight})
+			}
+		}
+		slices.SortFunc(row, func(a, b csrEntry) int {
+			return a.neighbor - b.neighbor
+		})
+		for idx, entry := range row {
+			if idx > 0 && entry.neighbor == row[idx-1].neighbor {
+				c.Weights[len(c.Weights)-1] += entry.weight
+				continue
+			}
+			c.Neighbors = append(c.Neighbors, entry.neighbor)
+			c.Weights = append(c.Weights, entry.weight)
+		}
+		c.Offsets[i+1] = len(c.Neighbors)
+	}
+	n.csr = c
+	return c
+}
+
+// InvalidateCSR marks the index based view of Adjacencies as stale
+func (n *SpatialNet) InvalidateCSR() {
+	n.csr = nil
+}
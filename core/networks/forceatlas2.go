@@ -0,0 +1,329 @@
+package networks
+
+import (
+	"math"
+)
+
+// ForceAtlas2Layout is the ForceAtlas2 layout from Gephi
+// (Jacomy et al. 2014). Repulsion follows 1/d, attraction follows
+// d (or log(1+d) in LinLog mode), and every node moves with its own
+// speed based on how much its force swings between steps.
+type ForceAtlas2Layout struct {
+	//Strength of repulsion relative to attraction
+	ScalingRatio float32
+
+	//Pull towards the origin, proportional to distance
+	//if StrongGravity is set
+	Gravity       float32
+	StrongGravity bool
+
+	//Use log(1+d) attraction, gives tighter clusters
+	LinLog bool
+
+	//Weight repulsion by (degree+1) of both nodes so hubs push harder
+	DegreeRepulsion bool
+
+	//Use node radii so nodes don't overlap
+	PreventOverlap bool
+
+	//Attraction is scaled by weight^EdgeWeightInfluence,
+	//0 ignores edge weights
+	EdgeWeightInfluence float32
+
+	//Tolerance to swinging, higher values move faster but less precisely
+	JitterTolerance float32
+
+	//Barnes-Hut approximation for repulsion, 0 computes it exactly
+	Theta float32
+
+	MaxWorkers uint
+
+	//adaptive speed state
+	speed, speedEfficiency float32
+	mass                   []float32
+	csr                    *CSR
+	fx, fy, prevFx, prevFy []float32
+}
+
+func NewForceAtlas2Layout() *ForceAtlas2Layout {
+	return &ForceAtlas2Layout{ScalingRatio: 2.0,
+		Gravity:             1.0,
+		DegreeRepulsion:     true,
+		EdgeWeightInfluence: 1.0,
+		JitterTolerance:     1.0,
+		Theta:               1.2,
+		MaxWorkers:          1}
+}
+
+func init() {
+	RegisterLayout("forceatlas2", func() Layout { return NewForceAtlas2Layout() })
+}
+
+func (fa *ForceAtlas2Layout) Init(n *SpatialNet) error {
+	count := len(n.NodeSlice)
+	fa.speed = 1.0
+	fa.speedEfficiency = 1.0
+	fa.mass = make([]float32, count)
+	fa.csr = n.CSR()
+	fa.fx = make([]float32, count)
+	fa.fy = make([]float32, count)
+	fa.prevFx = make([]float32, count)
+	fa.prevFy = make([]float32, count)
+
+	for i := range n.NodeSlice {
+		fa.mass[i] = 1.0
+		if fa.DegreeRepulsion {
+			fa.mass[i] += float32(fa.csr.Degree(i))
+		}
+	}
+	return nil
+}
+
+// repulsion between two nodes, returned as the force magnitude
+// pushing them apart
+func (fa *ForceAtlas2Layout) repulsion(a, b *SpatialNetNode, massA, massB, dist float32) float32 {
+	kr := fa.ScalingRatio
+	if fa.PreventOverlap {
+		gap := dist - a.Radius - b.Radius
+		if gap > 0 {
+			return kr * massA * massB / gap
+		} else if gap < 0 {
+			return 100 * kr * massA * massB
+		}
+		return 0.0
+	}
+	return kr * massA * massB / dist
+}
+
+func (fa *ForceAtlas2Layout) repulsionBarnesHut(qt *QuadTree, nodes []SpatialNetNode, idx int) (float32, float32) {
+	if qt.Mass == 0 || qt.Node == idx {
+		return 0.0, 0.0
+	}
+	node := &nodes[idx]
+	//the cells holding the node are always opened, otherwise
+	//its own mass would push it away from the center of mass
+	open := !qt.isLeaf() && qt.contains(node.X, node.Y)
+	if !open {
+		dx := node.X - qt.CX
+		dy := node.Y - qt.CY
+		dist := float32(math.Hypot(float64(dx), float64(dy)))
+		if dist == 0 {
+			return 0.0, 0.0
+		}
+
+		if qt.isLeaf() && qt.Node != -1 {
+			f := fa.repulsion(node, &nodes[qt.Node], fa.mass[idx], qt.Mass, dist)
+			return f * dx / dist, f * dy / dist
+		}
+		if qt.isLeaf() || qt.Size/dist < fa.Theta {
+			f := fa.ScalingRatio * fa.mass[idx] * qt.Mass / dist
+			return f * dx / dist, f * dy / dist
+		}
+	}
+
+	var fx, fy float32 = 0.0, 0.0
+	for _, child := range qt.Children {
+		cfx, cfy := fa.repulsionBarnesHut(child, nodes, idx)
+		fx += cfx
+		fy += cfy
+	}
+	return fx, fy
+}
+
+func (fa *ForceAtlas2Layout) Step(n *SpatialNet) {
+	count := len(n.NodeSlice)
+	if count == 0 {
+		return
+	}
+	if len(fa.mass) != count || fa.csr != n.CSR() {
+		fa.Init(n)
+	}
+
+	copy(fa.prevFx, fa.fx)
+	copy(fa.prevFy, fa.fy)
+
+	var qt *QuadTree
+	if fa.Theta > 0 {
+		qt = n.NewWeightedQuadTree(fa.mass)
+	}
+
+	parallelFor(count, fa.MaxWorkers, func(i int) {
+		nodeA := &n.NodeSlice[i]
+		var fx, fy float32 = 0.0, 0.0
+
+		//repulsion
+		if qt != nil {
+			fx, fy = fa.repulsionBarnesHut(qt, n.NodeSlice, i)
+		} else {
+			for j := range count {
+				if i == j {
+					continue
+				}
+				nodeB := &n.NodeSlice[j]
+				dx := nodeA.X - nodeB.X
+				dy := nodeA.Y - nodeB.Y
+				dist := float32(math.Hypot(float64(dx), float64(dy)))
+				if dist == 0 {
+					continue
+				}
+				f := fa.repulsion(nodeA, nodeB, fa.mass[i], fa.mass[j], dist)
+				fx += f * dx / dist
+				fy += f * dy / dist
+			}
+		}
+
+		//gravity towards the origin
+		dist := float32(math.Hypot(float64(nodeA.X), float64(nodeA.Y)))
+		if dist > 0 {
+			g := fa.Gravity * fa.mass[i]
+			if !fa.StrongGravity {
+				g /= dist
+			}
+			fx -= g * nodeA.X
+			fy -= g * nodeA.Y
+		}
+
+		//attraction along edges
+		weights := fa.csr.NodeWeights(i)
+		for idx, j := range fa.csr.NodeNeighbors(i) {
+			if i == j {
+				continue
+			}
+			nodeB := &n.NodeSlice[j]
+			dx := nodeB.X - nodeA.X
+			dy := nodeB.Y - nodeA.Y
+			dist := float32(math.Hypot(float64(dx), float64(dy)))
+			if fa.PreventOverlap {
+				dist -= nodeA.Radius + nodeB.Radius
+			}
+			if dist <= 0 {
+				continue
+			}
+			var f float32
+			if fa.LinLog {
+				f = float32(math.Log(1.0+float64(dist))) / dist
+			} else {
+				f = 1.0
+			}
+			if fa.EdgeWeightInfluence != 0 {
+				f *= float32(math.Pow(float64(weights[idx]), float64(fa.EdgeWeightInfluence)))
+			}
+			fx += f * dx
+			fy += f * dy
+		}
+
+		fa.fx[i] = fx
+		fa.fy[i] = fy
+	})
+
+	fa.adjustSpeed(count)
+
+	for i := range n.NodeSlice {
+		node := &n.NodeSlice[i]
+		swinging := fa.mass[i] * float32(math.Hypot(float64(fa.fx[i]-fa.prevFx[i]), float64(fa.fy[i]-fa.prevFy[i])))
+		factor := fa.speed / (1.0 + float32(math.Sqrt(float64(fa.speed*swinging))))
+		if fa.PreventOverlap {
+			factor *= 0.1
+			force := float32(math.Hypot(float64(fa.fx[i]), float64(fa.fy[i])))
+			if force > 0 {
+				factor = min(factor*force, 10.0) / force
+			}
+		}
+		node.Vx = factor * fa.fx[i]
+		node.Vy = factor * fa.fy[i]
+		node.move(node.Vx, node.Vy)
+	}
+}
+
+// adjustSpeed updates the global speed from the total swinging and
+// traction of every node, following the Gephi implementation
+func (fa *ForceAtlas2Layout) adjustSpeed(count int) {
+	var totalSwinging, totalTraction float64
+	for i := range count {
+		m := float64(fa.mass[i])
+		totalSwinging += m * math.Hypot(float64(fa.fx[i]-fa.prevFx[i]), float64(fa.fy[i]-fa.prevFy[i]))
+		totalTraction += m * 0.5 * math.Hypot(float64(fa.fx[i]+fa.prevFx[i]), float64(fa.fy[i]+fa.prevFy[i]))
+	}
+	if totalSwinging == 0 || totalTraction == 0 {
+		return
+	}
+
+	estimatedJitter := 0.05 * math.Sqrt(float64(count))
+	minJitter := math.Sqrt(estimatedJitter)
+	maxJitter := 10.0
+	jitter := float64(fa.JitterTolerance) * max(minJitter,
+		min(maxJitter, estimatedJitter*totalTraction/float64(count*count)))
+
+	minSpeedEfficiency := float32(0.05)
+	if totalSwinging/totalTraction > 2.0 {
+		if fa.speedEfficiency > minSpeedEfficiency {
+			fa.speedEfficiency *= 0.5
+		}
+		jitter = max(jitter, float64(fa.JitterTolerance))
+	}
+
+	targetSpeed := float32(jitter*totalTraction/totalSwinging) * fa.speedEfficiency
+
+	if totalSwinging > jitter*totalTraction {
+		if fa.speedEfficiency > minSpeedEfficiency {
+			fa.speedEfficiency *= 0.7
+		}
+	} else if fa.speed < 1000 {
+		fa.speedEfficiency *= 1.3
+	}
+
+	maxRise := float32(0.5)
+	fa.speed += min(targetSpeed-fa.speed, maxRise*fa.speed)
+}
+
+func (fa *ForceAtlas2Layout) Converged() bool {
+	return false
+}
+
+func boolParameter(b bool) float32 {
+	if b {
+		return 1.0
+	}
+	return 0.0
+}
+
+func (fa *ForceAtlas2Layout) Parameters() map[string]float32 {
+	return map[string]float32{"scalingRatio": fa.ScalingRatio,
+		"gravity":             fa.Gravity,
+		"strongGravity":       boolParameter(fa.StrongGravity),
+		"linLog":              boolParameter(fa.LinLog),
+		"degreeRepulsion":     boolParameter(fa.DegreeRepulsion),
+		"preventOverlap":      boolParameter(fa.PreventOverlap),
+		"edgeWeightInfluence": fa.EdgeWeightInfluence,
+		"jitterTolerance":     fa.JitterTolerance,
+		"theta":               fa.Theta,
+		"maxWorkers":          float32(fa.MaxWorkers)}
+}
+
+func (fa *ForceAtlas2Layout) SetParameter(name string, value float32) error {
+	switch name {
+	case "scalingRatio":
+		fa.ScalingRatio = value
+	case "gravity":
+		fa.Gravity = value
+	case "strongGravity":
+		fa.StrongGravity = value != 0
+	case "linLog":
+		fa.LinLog = value != 0
+	case "degreeRepulsion":
+		fa.DegreeRepulsion = value != 0
+	case "preventOverlap":
+		fa.PreventOverlap = value != 0
+	case "edgeWeightInfluence":
+		fa.EdgeWeightInfluence = value
+	case "jitterTolerance":
+		fa.JitterTolerance = value
+	case "theta":
+		fa.Theta = value
+	case "maxWorkers":
+		fa.MaxWorkers = uint(max(value, 1))
+	default:
+		return unknownParameter(name)
+	}
+	return nil
+}
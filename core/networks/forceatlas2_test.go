@@ -0,0 +1,37 @@
+package networks
+
+import (
+	"math"
+	"testing"
+)
+
+func TestForceAtlas2BarnesHutOpensOwnCell(t *testing.T) {
+	//with a huge theta every cell would be approximated, so the
+	//force only matches the exact one if the node's own cells are opened
+	n := NewSpatialNet()
+	n.AddNode("a")
+	n.AddNode("b")
+	if err := n.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	n.NodeSlice[0].X, n.NodeSlice[0].Y = 0.0, 0.0
+	n.NodeSlice[1].X, n.NodeSlice[1].Y = 10.0, 3.0
+
+	fa := NewForceAtlas2Layout()
+	fa.Theta = 1e9
+	fa.Init(n)
+	qt := n.NewWeightedQuadTree(fa.mass)
+	for i := range n.NodeSlice {
+		other := &n.NodeSlice[1-i]
+		node := &n.NodeSlice[i]
+		dx := node.X - other.X
+		dy := node.Y - other.Y
+		dist := float32(math.Hypot(float64(dx), float64(dy)))
+		f := fa.repulsion(node, other, fa.mass[i], fa.mass[1-i], dist)
+		wantX, wantY := f*dx/dist, f*dy/dist
+		gotX, gotY := fa.repulsionBarnesHut(qt, n.NodeSlice, i)
+		if math.Hypot(float64(gotX-wantX), float64(gotY-wantY)) > 1e-4 {
+			t.Errorf("node %d: repulsion (%v, %v), want (%v, %v)", i, gotX, gotY, wantX, wantY)
+		}
+	}
+}
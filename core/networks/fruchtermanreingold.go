@@ -0,0 +1,225 @@
+package networks
+
+import (
+	"math"
+)
+
+// CoolingSchedule returns the temperature (maximum displacement of a
+// node) for a given iteration of a layout
+type CoolingSchedule func(iteration int, initialTemperature float32) float32
+
+type CoolingMode int
+
+const (
+	CoolingLinear CoolingMode = iota
+	CoolingExponential
+	CoolingCustom
+)
+
+// LinearCooling lowers the temperature to zero over the given number of iterations
+func LinearCooling(iterations int) CoolingSchedule {
+	return func(iteration int, initialTemperature float32) float32 {
+		if iteration >= iterations {
+			return 0.0
+		}
+		return initialTemperature * (1.0 - float32(iteration)/float32(iterations))
+	}
+}
+
+// ExponentialCooling multiplies the temperature by rate every iteration
+func ExponentialCooling(rate float32) CoolingSchedule {
+	return func(iteration int, initialTemperature float32) float32 {
+		return initialTemperature * float32(math.Pow(float64(rate), float64(iteration)))
+	}
+}
+
+// FruchtermanReingoldUpdate performs one step of the Fruchterman-Reingold
+// layout. Nodes repel with k^2/d and neighbors attract with d^2/k, where k
+// is the ideal edge length. Each node moves at most temperature, and stays
+// inside a width by height frame centered on the origin.
+func (n *SpatialNet) FruchtermanReingoldUpdate(k,
+	temperature,
+	width,
+	height float32,
+	maxWorkers uint) {
+
+	csr := n.CSR()
+	parallelFor(len(n.NodeSlice), maxWorkers, func(i int) {
+		nodeA := &n.NodeSlice[i]
+		var fx, fy float32 = 0.0, 0.0
+		for j := range n.NodeSlice {
+			if i == j {
+				continue
+			}
+			nodeB := &n.NodeSlice[j]
+			dx := nodeA.X - nodeB.X
+			dy := nodeA.Y - nodeB.Y
+			dist := max(float32(math.Hypot(float64(dx), float64(dy))), 0.01)
+			f := k * k / dist
+			fx += f * dx / dist
+			fy += f * dy / dist
+		}
+		for _, j := range csr.NodeNeighbors(i) {
+			if i == j {
+				continue
+			}
+			nodeB := &n.NodeSlice[j]
+			dx := nodeA.X - nodeB.X
+			dy := nodeA.Y - nodeB.Y
+			dist := max(float32(math.Hypot(float64(dx), float64(dy))), 0.01)
+			f := -1.0 * dist * dist / k
+			fx += f * dx / dist
+			fy += f * dy / dist
+		}
+		nodeA.Vx = fx
+		nodeA.Vy = fy
+	})
+
+	halfWidth := width / 2
+	halfHeight := height / 2
+	for i := range n.NodeSlice {
+		node := &n.NodeSlice[i]
+		force := float32(math.Hypot(float64(node.Vx), float64(node.Vy)))
+		if force > 0 {
+			scale := min(force, temperature) / force
+			node.Vx *= scale
+			node.Vy *= scale
+		}
+		oldX, oldY := node.X, node.Y
+		if !node.Pinned {
+			node.X = min(halfWidth, max(-halfWidth, node.X+node.Vx))
+			node.Y = min(halfHeight, max(-halfHeight, node.Y+node.Vy))
+			node.constrain()
+		}
+		node.Vx = node.X - oldX
+		node.Vy = node.Y - oldY
+	}
+}
+
+// FruchtermanReingoldLayout runs FruchtermanReingoldUpdate as a Layout,
+// cooling the temperature every step until nodes stop moving
+type FruchtermanReingoldLayout struct {
+	//Frame the nodes are kept in, centered on the origin
+	Width, Height float32
+
+	//Temperature of the first iteration, and the temperature
+	//at which the layout counts as converged
+	InitialTemperature, MinTemperature float32
+
+	//Cooling schedule, CustomCooling is used with CoolingCustom
+	CoolingMode   CoolingMode
+	CoolingRate   float32
+	Iterations    int
+	CustomCooling CoolingSchedule
+
+	//Start from the current node positions instead of scattering
+	//the nodes randomly across the frame
+	KeepPositions bool
+
+	MaxWorkers uint
+
+	iteration   int
+	temperature float32
+}
+
+func NewFruchtermanReingoldLayout() *FruchtermanReingoldLayout {
+	return &FruchtermanReingoldLayout{Width: 1000.0,
+		Height:             1000.0,
+		InitialTemperature: 100.0,
+		MinTemperature:     0.01,
+		CoolingMode:        CoolingLinear,
+		CoolingRate:        0.99,
+		Iterations:         500,
+		MaxWorkers:         1}
+}
+
+func init() {
+	RegisterLayout("fruchtermanreingold", func() Layout { return NewFruchtermanReingoldLayout() })
+}
+
+func (fr *FruchtermanReingoldLayout) Init(n *SpatialNet) error {
+	fr.iteration = 0
+	fr.temperature = fr.InitialTemperature
+	if !fr.KeepPositions {
+		rng := n.random()
+		for i := range n.NodeSlice {
+			x := (rng.Float32() - 0.5) * fr.Width
+			y := (rng.Float32() - 0.5) * fr.Height
+			if !n.NodeSlice[i].Pinned {
+				n.NodeSlice[i].X = x
+				n.NodeSlice[i].Y = y
+				n.NodeSlice[i].constrain()
+			}
+		}
+	}
+	for i := range n.NodeSlice {
+		n.NodeSlice[i].Vx = 0.0
+		n.NodeSlice[i].Vy = 0.0
+	}
+	return nil
+}
+
+// Temperature returns the temperature of the given iteration
+func (fr *FruchtermanReingoldLayout) Temperature(iteration int) float32 {
+	switch fr.CoolingMode {
+	case CoolingExponential:
+		return ExponentialCooling(fr.CoolingRate)(iteration, fr.InitialTemperature)
+	case CoolingCustom:
+		if fr.CustomCooling != nil {
+			return fr.CustomCooling(iteration, fr.InitialTemperature)
+		}
+	}
+	return LinearCooling(fr.Iterations)(iteration, fr.InitialTemperature)
+}
+
+func (fr *FruchtermanReingoldLayout) Step(n *SpatialNet) {
+	if len(n.NodeSlice) == 0 {
+		return
+	}
+	fr.temperature = fr.Temperature(fr.iteration)
+	k := float32(math.Sqrt(float64(fr.Width*fr.Height) / float64(len(n.NodeSlice))))
+	n.FruchtermanReingoldUpdate(k, fr.temperature, fr.Width, fr.Height, fr.MaxWorkers)
+	fr.iteration++
+}
+
+func (fr *FruchtermanReingoldLayout) Converged() bool {
+	return fr.iteration > 0 && fr.temperature <= fr.MinTemperature
+}
+
+func (fr *FruchtermanReingoldLayout) Parameters() map[string]float32 {
+	return map[string]float32{"width": fr.Width,
+		"height":             fr.Height,
+		"initialTemperature": fr.InitialTemperature,
+		"minTemperature":     fr.MinTemperature,
+		"cooling":            float32(fr.CoolingMode),
+		"coolingRate":        fr.CoolingRate,
+		"iterations":         float32(fr.Iterations),
+		"keepPositions":      boolParameter(fr.KeepPositions),
+		"maxWorkers":         float32(fr.MaxWorkers)}
+}
+
+func (fr *FruchtermanReingoldLayout) SetParameter(name string, value float32) error {
+	switch name {
+	case "width":
+		fr.Width = value
+	case "height":
+		fr.Height = value
+	case "initialTemperature":
+		fr.InitialTemperature = value
+	case "minTemperature":
+		fr.MinTemperature = value
+	case "cooling":
+		fr.CoolingMode = CoolingMode(value)
+	case "coolingRate":
+		fr.CoolingRate = value
+	case "iterations":
+		fr.Iterations = int(value)
+	case "keepPositions":
+		fr.KeepPositions = value != 0
+	case "maxWorkers":
+		fr.MaxWorkers = uint(max(value, 1))
+	default:
+		return unknownParameter(name)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package networks
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// Generators build undirected networks with nodes named "0", "1", ...
+// Every generator draws from a generator seeded with seed, which the
+// network keeps as Rng, and scatters nodes the same way the loaders do
+// unless the model itself places them. They run in time proportional
+// to the number of nodes and edges, not the number of node pairs.
+
+// newGeneratedNet returns a network with count nodes
+// scattered randomly around the origin
+func newGeneratedNet(count int, seed int64) *SpatialNet {
+	n := NewSpatialNet()
+	n.Seed(seed)
+	n.NodeSlice = make([]SpatialNetNode, 0, count)
+	for i := range count {
+		n.AddNode(strconv.Itoa(i))
+		n.NodeSlice[i].X = (100.0 * n.Rng.Float32()) - 50.0
+		n.NodeSlice[i].Y = (100.0 * n.Rng.Float32()) - 50.0
+	}
+	return n
+}
+
+func (n *SpatialNet) addEdgeIndeces(i, j int) {
+	n.AddEdge(n.NodeSlice[i].Name, n.NodeSlice[j].Name)
+}
+
+// geometricSkip returns how many candidate pairs to skip before the
+// next edge when each pair is an edge with probability p, which lets
+// the sparse generators jump straight from one edge to the next
+func geometricSkip(rng *rand.Rand, logOneMinusP float64) int {
+	return int(math.Floor(math.Log(1.0-rng.Float64()) / logOneMinusP))
+}
+
+// addRandomPairs adds each pair of nodes first <= j < i < first+count
+// as an edge with probability p (Batagelj and Brandes 2005)
+func (n *SpatialNet) addRandomPairs(first, count int, p float64) {
+	if p <= 0 || count < 2 {
+		return
+	}
+	logOneMinusP := math.Log(1.0 - p)
+	v, w := 1, -1
+	for v < count {
+		w += 1 + geometricSkip(n.Rng, logOneMinusP)
+		for w >= v && v < count {
+			w -= v
+			v++
+		}
+		if v < count {
+			n.addEdgeIndeces(first+v, first+w)
+		}
+	}
+}
+
+// addRandomBipartitePairs adds each pair of a node in
+// [firstA, firstA+countA) and a node in [firstB, firstB+countB)
+// as an edge with probability p
+func (n *SpatialNet) addRandomBipartitePairs(firstA, countA, firstB, countB int, p float64) {
+	if p <= 0 {
+		return
+	}
+	logOneMinusP := math.Log(1.0 - p)
+	total := countA * countB
+	for idx := -1; ; {
+		idx += 1 + geometricSkip(n.Rng, logOneMinusP)
+		if idx >= total {
+			return
+		}
+		n.addEdgeIndeces(firstA+idx/countB, firstB+idx%countB)
+	}
+}
+
+// NewErdosRenyiSpatialNet returns a network where every pair of nodes
+// is linked with probability edgeProb, like NewRandomSpatialNet but
+// without visiting every pair
+func NewErdosRenyiSpatialNet(numNodes int, edgeProb float32, seed int64) (*SpatialNet, error) {
+	if numNodes < 0 || edgeProb < 0 || edgeProb > 1 {
+		return nil, errors.New("Erdos-Renyi needs a node count >= 0 and an edge probability in [0, 1]")
+	}
+	n := newGeneratedNet(numNodes, seed)
+	n.addRandomPairs(0, numNodes, float64(edgeProb))
+	return n, nil
+}
+
+// NewBarabasiAlbertSpatialNet returns a scale free network grown by
+// preferential attachment. Each new node links to m existing nodes
+// picked with probability proportional to their degree.
+func NewBarabasiAlbertSpatialNet(numNodes, m int, seed int64) (*SpatialNet, error) {
+	if m < 1 || m >= numNodes {
+		return nil, errors.New("Barabasi-Albert needs 1 <= m < number of nodes")
+	}
+	n := newGeneratedNet(numNodes, seed)
+
+	//every node appears once per edge it has, so picking
+	//uniformly from repeated is picking by degree
+	repeated := make([]int, 0, 2*m*numNodes)
+	targets := make([]int, m)
+	for i := range targets {
+		targets[i] = i
+	}
+	chosen := make(map[int]bool, m)
+	for source := m; source < numNodes; source++ {
+		for _, target := range targets {
+			n.addEdgeIndeces(source, target)
+			repeated = append(repeated, target, source)
+		}
+		clear(chosen)
+		targets = targets[:0]
+		for len(targets) < m {
+			target := repeated[n.Rng.Intn(len(repeated))]
+			if !chosen[target] {
+				chosen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return n, nil
+}
+
+// NewWattsStrogatzSpatialNet returns a small world network: a ring where
+// every node links to its k nearest neighbors, with each edge rewired
+// to a random node with probability beta
+func NewWattsStrogatzSpatialNet(numNodes, k int, beta float32, seed int64) (*SpatialNet, error) {
+	if k < 2 || k%2 != 0 || k >= numNodes {
+		return nil, errors.New("Watts-Strogatz needs an even k with 2 <= k < number of nodes")
+	}
+	if beta < 0 || beta > 1 {
+		return nil, errors.New("Watts-Strogatz needs a rewiring probability in [0, 1]")
+	}
+	n := newGeneratedNet(numNodes, seed)
+	for offset := 1; offset <= k/2; offset++ {
+		for u := range numNodes {
+			n.addEdgeIndeces(u, (u+offset)%numNodes)
+		}
+	}
+
+	for offset := 1; offset <= k/2; offset++ {
+		for u := range numNodes {
+			nameU := n.NodeSlice[u].Name
+			nameV := n.NodeSlice[(u+offset)%numNodes].Name
+			if n.Rng.Float32() >= beta || !n.ContainsEdge(nameU, nameV) {
+				continue
+			}
+			//a node linked to everything has nowhere to rewire to
+			if n.OutDegree(nameU) >= numNodes-1 {
+				continue
+			}
+			w := n.Rng.Intn(numNodes)
+			for w == u || n.ContainsEdge(nameU, n.NodeSlice[w].Name) {
+				w = n.Rng.Intn(numNodes)
+			}
+			n.RemoveEdge(nameU, nameV)
+			n.addEdgeIndeces(u, w)
+		}
+	}
+	return n, nil
+}
+
+// NewStochasticBlockSpatialNet returns a network with planted communities.
+// Block b holds sizes[b] consecutive nodes, and a node in block a links to
+// a node in block b with probability probs[a][b], which must be symmetric.
+// The block of every node is stored in the int node attribute "block".
+func NewStochasticBlockSpatialNet(sizes []int, probs [][]float32, seed int64) (*SpatialNet, error) {
+	if len(probs) != len(sizes) {
+		return nil, errors.New("stochastic block model needs one row of probabilities per block")
+	}
+	for a := range probs {
+		if len(probs[a]) != len(sizes) || sizes[a] < 0 {
+			return nil, errors.New("stochastic block model needs a square probability matrix and sizes >= 0")
+		}
+		for b := range probs[a] {
+			if probs[a][b] < 0 || probs[a][b] > 1 || probs[a][b] != probs[b][a] {
+				return nil, errors.New("stochastic block model needs symmetric probabilities in [0, 1]")
+			}
+		}
+	}
+
+	total := 0
+	firsts := make([]int, len(sizes))
+	for b, size := range sizes {
+		firsts[b] = total
+		total += size
+	}
+	n := newGeneratedNet(total, seed)
+	for b, size := range sizes {
+		for i := firsts[b]; i < firsts[b]+size; i++ {
+			n.NodeAttributes.SetInt(n.NodeSlice[i].Name, "block", b)
+		}
+	}
+
+	for a := range sizes {
+		n.addRandomPairs(firsts[a], sizes[a], float64(probs[a][a]))
+		for b := a + 1; b < len(sizes); b++ {
+			n.addRandomBipartitePairs(firsts[a], sizes[a], firsts[b], sizes[b], float64(probs[a][b]))
+		}
+	}
+	return n, nil
+}
+
+// NewLatticeSpatialNet returns a grid with dims[d] nodes along dimension
+// d, so two dims give a 2D lattice and three a 3D lattice. Each node links
+// to its neighbor on either side along every dimension, wrapping around
+// the edges when periodic is set.
+func NewLatticeSpatialNet(dims []int, periodic bool, seed int64) (*SpatialNet, error) {
+	if len(dims) == 0 {
+		return nil, errors.New("lattice needs at least one dimension")
+	}
+	total := 1
+	for _, d := range dims {
+		if d < 1 {
+			return nil, errors.New("lattice needs at least one node along every dimension")
+		}
+		total *= d
+	}
+	n := newGeneratedNet(total, seed)
+
+	//nodes are numbered with the first dimension varying fastest
+	stride := 1
+	for _, d := range dims {
+		for i := range total {
+			coord := (i / stride) % d
+			if coord+1 < d {
+				n.addEdgeIndeces(i, i+stride)
+			} else if periodic && d > 2 {
+				n.addEdgeIndeces(i, i-coord*stride)
+			}
+		}
+		stride *= d
+	}
+	return n, nil
+}
+
+// NewBalancedTreeSpatialNet returns a tree where every node
+// above depth height has branching children
+func NewBalancedTreeSpatialNet(branching, height int, seed int64) (*SpatialNet, error) {
+	if branching < 1 || height < 0 {
+		return nil, errors.New("balanced tree needs branching >= 1 and height >= 0")
+	}
+	total := 1
+	levelSize := 1
+	for range height {
+		levelSize *= branching
+		total += levelSize
+	}
+	n := newGeneratedNet(total, seed)
+
+	//children of node i are numbered branching*i+1 to branching*i+branching
+	for child := 1; child < total; child++ {
+		n.addEdgeIndeces((child-1)/branching, child)
+	}
+	return n, nil
+}
+
+// NewRandomGeometricSpatialNet returns a network of nodes placed uniformly
+// in a size by size square centered on the origin, linking every pair of
+// nodes closer than radius. Nodes keep the positions they were placed at.
+func NewRandomGeometricSpatialNet(numNodes int, radius, size float32, seed int64) (*SpatialNet, error) {
+	if numNodes < 0 || radius <= 0 || size <= 0 {
+		return nil, errors.New("random geometric network needs a positive radius and size")
+	}
+	n := newGeneratedNet(numNodes, seed)
+	for i := range n.NodeSlice {
+		n.NodeSlice[i].X = (n.Rng.Float32() - 0.5) * size
+		n.NodeSlice[i].Y = (n.Rng.Float32() - 0.5) * size
+	}
+
+	//only nodes in the same or a neighboring cell of
+	//side radius can be close enough to link
+	cells := make(map[[2]int][]int)
+	for i := range n.NodeSlice {
+		cell := n.NodeSlice[i].GetBin(radius)
+		cells[cell] = append(cells[cell], i)
+	}
+	for i := range n.NodeSlice {
+		a := &n.NodeSlice[i]
+		cell := a.GetBin(radius)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range cells[[2]int{cell[0] + dx, cell[1] + dy}] {
+					if j <= i {
+						continue
+					}
+					b := &n.NodeSlice[j]
+					if math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y)) < float64(radius) {
+						n.addEdgeIndeces(i, j)
+					}
+				}
+			}
+		}
+	}
+	return n, nil
+}
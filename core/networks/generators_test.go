@@ -0,0 +1,135 @@
+package networks
+
+import (
+	"strconv"
+	"testing"
+)
+
+// undirectedEdgeCount returns the number of edges in an undirected
+// network, counted from the adjacencies, and its degree sum, failing
+// on self loops
+func undirectedEdgeCount(t *testing.T, n *SpatialNet) (int, int) {
+	t.Helper()
+	edges, degreeSum := 0, 0
+	for _, node := range n.NodeSlice {
+		for nbr := range n.Adjacencies[node.Name] {
+			if nbr == node.Name {
+				t.Fatalf("node %s links to itself", node.Name)
+			}
+			if n.NodeIndeces[nbr] > n.NodeIndeces[node.Name] {
+				edges++
+			}
+		}
+		degreeSum += n.Degree(node.Name)
+	}
+	return edges, degreeSum
+}
+
+func TestWattsStrogatzEdgeCount(t *testing.T) {
+	tests := []struct {
+		nodes, k int
+		beta     float32
+	}{
+		{20, 2, 0.0},
+		{20, 4, 0.0},
+		{20, 4, 0.3},
+		{20, 4, 1.0},
+		//a ring of 7 with k 6 is complete, so nothing can be rewired
+		{7, 6, 1.0},
+		{200, 10, 0.5},
+	}
+	for _, test := range tests {
+		for seed := range int64(3) {
+			n, err := NewWattsStrogatzSpatialNet(test.nodes, test.k, test.beta, seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(n.NodeSlice) != test.nodes {
+				t.Errorf("%d nodes k %d beta %v: got %d nodes", test.nodes, test.k, test.beta, len(n.NodeSlice))
+			}
+			//rewiring moves edges but never adds or drops one
+			edges, _ := undirectedEdgeCount(t, n)
+			if want := test.nodes * test.k / 2; edges != want {
+				t.Errorf("%d nodes k %d beta %v seed %d: got %d edges, want %d",
+					test.nodes, test.k, test.beta, seed, edges, want)
+			}
+			if test.beta == 0 {
+				for _, node := range n.NodeSlice {
+					if n.Degree(node.Name) != test.k {
+						t.Errorf("%d nodes k %d: node %s has degree %d before rewiring",
+							test.nodes, test.k, node.Name, n.Degree(node.Name))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLatticeEdgeCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		dims     []int
+		periodic bool
+		edges    int
+	}{
+		{"single node", []int{1}, true, 0},
+		//a periodic dimension of 2 would wrap onto the edge it already has
+		{"pair", []int{2}, true, 1},
+		{"ring", []int{5}, true, 5},
+		{"path", []int{5}, false, 4},
+		{"1 by 4", []int{1, 4}, true, 4},
+		{"2 by 2", []int{2, 2}, true, 4},
+		{"2 by 3", []int{2, 3}, true, 3 + 2*3},
+		{"2 by 3 open", []int{2, 3}, false, 3 + 2*2},
+		{"3 by 3 torus", []int{3, 3}, true, 18},
+		{"2 by 2 by 2", []int{2, 2, 2}, true, 12},
+	}
+	for _, test := range tests {
+		n, err := NewLatticeSpatialNet(test.dims, test.periodic, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 1
+		for _, d := range test.dims {
+			want *= d
+		}
+		if len(n.NodeSlice) != want {
+			t.Errorf("%s: got %d nodes, want %d", test.name, len(n.NodeSlice), want)
+		}
+		if edges, _ := undirectedEdgeCount(t, n); edges != test.edges {
+			t.Errorf("%s: got %d edges, want %d", test.name, edges, test.edges)
+		}
+	}
+}
+
+func TestBarabasiAlbertDegrees(t *testing.T) {
+	for _, m := range []int{1, 2, 5} {
+		for seed := range int64(3) {
+			const nodes = 100
+			n, err := NewBarabasiAlbertSpatialNet(nodes, m, seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			edges, degreeSum := undirectedEdgeCount(t, n)
+			if want := m * (nodes - m); edges != want {
+				t.Errorf("m %d seed %d: got %d edges, want %d", m, seed, edges, want)
+			}
+			if degreeSum != 2*edges {
+				t.Errorf("m %d seed %d: degree sum %d for %d edges", m, seed, degreeSum, edges)
+			}
+
+			//every node after the first m links to m nodes that came before it
+			for source := m; source < nodes; source++ {
+				earlier := 0
+				for target := range source {
+					if n.ContainsEdge(strconv.Itoa(source), strconv.Itoa(target)) {
+						earlier++
+					}
+				}
+				if earlier != m {
+					t.Errorf("m %d seed %d: node %d links to %d earlier nodes", m, seed, source, earlier)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package networks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Layout is a layout algorithm that moves the nodes of a SpatialNet.
+// A layout is initialized once for a network and then stepped until
+// it converges or the caller runs out of iterations.
+type Layout interface {
+	//Init prepares the layout to run on the given network
+	Init(n *SpatialNet) error
+
+	//Step advances the layout by a single iteration
+	Step(n *SpatialNet)
+
+	//Converged reports whether further steps would not
+	//change the layout meaningfully
+	Converged() bool
+
+	//Parameters returns the current value of every tunable
+	//parameter of the layout, keyed by name
+	Parameters() map[string]float32
+
+	//SetParameter sets one of the parameters returned by Parameters
+	SetParameter(name string, value float32) error
+}
+
+// LayoutFactory returns a new layout with its default parameters
+type LayoutFactory func() Layout
+
+var layoutRegistry = struct {
+	sync.RWMutex
+	factories map[string]LayoutFactory
+}{factories: make(map[string]LayoutFactory)}
+
+// RegisterLayout makes a layout available to NewLayout under the given name
+func RegisterLayout(name string, factory LayoutFactory) error {
+	if name == "" || factory == nil {
+		return errors.New("cannot register a layout without a name and factory")
+	}
+	layoutRegistry.Lock()
+	defer layoutRegistry.Unlock()
+	if _, exists := layoutRegistry.factories[name]; exists {
+		return errors.New("attempted to register layout named " + name + " twice")
+	}
+	layoutRegistry.factories[name] = factory
+	return nil
+}
+
+// NewLayout creates a layout that was registered with RegisterLayout
+func NewLayout(name string) (Layout, error) {
+	layoutRegistry.RLock()
+	factory, exists := layoutRegistry.factories[name]
+	layoutRegistry.RUnlock()
+	if !exists {
+		return nil, errors.New("no layout registered with name " + name)
+	}
+	return factory(), nil
+}
+
+// LayoutNames returns the names of all registered layouts in sorted order
+func LayoutNames() []string {
+	layoutRegistry.RLock()
+	defer layoutRegistry.RUnlock()
+	names := make([]string, 0, len(layoutRegistry.factories))
+	for name := range layoutRegistry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unknownParameter(name string) error {
+	return errors.New("layout has no parameter named " + name)
+}
+
+func init() {
+	RegisterLayout("spring", func() Layout { return NewSpringLayout() })
+	RegisterLayout("barneshut", func() Layout { return NewBarnesHutLayout() })
+	RegisterLayout("hashing", func() Layout { return NewHashingLayout() })
+}
+
+// SpringLayout runs SpringUpdateParallel as a Layout
+type SpringLayout struct {
+	SpringConstant, StepSize, Equilibrium, Repulsion, Friction float32
+	MaxWorkers                                                 uint
+	EdgeWeights                                                WeightMode
+}
+
+func NewSpringLayout() *SpringLayout {
+	return &SpringLayout{SpringConstant: 0.1,
+		StepSize:    0.1,
+		Equilibrium: 8.0,
+		Repulsion:   80.0,
+		Friction:    0.125,
+		MaxWorkers:  1}
+}
+
+func (s *SpringLayout) Init(n *SpatialNet) error {
+	return nil
+}
+
+func (s *SpringLayout) Step(n *SpatialNet) {
+	n.SpringUpdateParallel(s.SpringConstant,
+		s.StepSize,
+		s.Equilibrium,
+		s.Repulsion,
+		s.Friction,
+		s.MaxWorkers,
+		s.EdgeWeights)
+}
+
+func (s *SpringLayout) Converged() bool {
+	return false
+}
+
+func (s *SpringLayout) Parameters() map[string]float32 {
+	return map[string]float32{"springConstant": s.SpringConstant,
+		"stepSize":    s.StepSize,
+		"equilibrium": s.Equilibrium,
+		"repulsion":   s.Repulsion,
+		"friction":    s.Friction,
+		"maxWorkers":  float32(s.MaxWorkers),
+		"edgeWeights": float32(s.EdgeWeights)}
+}
+
+func (s *SpringLayout) SetParameter(name string, value float32) error {
+	switch name {
+	case "springConstant":
+		s.SpringConstant = value
+	case "stepSize":
+		s.StepSize = value
+	case "equilibrium":
+		s.Equilibrium = value
+	case "repulsion":
+		s.Repulsion = value
+	case "friction":
+		s.Friction = value
+	case "maxWorkers":
+		s.MaxWorkers = uint(max(value, 1))
+	case "edgeWeights":
+		s.EdgeWeights = WeightMode(value)
+	default:
+		return unknownParameter(name)
+	}
+	return nil
+}
+
+// BarnesHutLayout runs SpringUpdateBarnesHut as a Layout
+type BarnesHutLayout struct {
+	SpringLayout
+	Theta float32
+}
+
+func NewBarnesHutLayout() *BarnesHutLayout {
+	return &BarnesHutLayout{SpringLayout: *NewSpringLayout(), Theta: 0.8}
+}
+
+func (b *BarnesHutLayout) Step(n *SpatialNet) {
+	n.SpringUpdateBarnesHut(b.SpringConstant,
+		b.StepSize,
+		b.Equilibrium,
+		b.Repulsion,
+		b.Friction,
+		b.Theta,
+		b.MaxWorkers,
+		b.EdgeWeights)
+}
+
+func (b *BarnesHutLayout) Parameters() map[string]float32 {
+	params := b.SpringLayout.Parameters()
+	params["theta"] = b.Theta
+	return params
+}
+
+func (b *BarnesHutLayout) SetParameter(name string, value float32) error {
+	if name == "theta" {
+		b.Theta = value
+		return nil
+	}
+	return b.SpringLayout.SetParameter(name, value)
+}
+
+// HashingLayout runs SpringUpdateHashingParallel as a Layout
+type HashingLayout struct {
+	SpringLayout
+	BinSize float32
+}
+
+func NewHashingLayout() *HashingLayout {
+	return &HashingLayout{SpringLayout: *NewSpringLayout(), BinSize: 50.0}
+}
+
+func (h *HashingLayout) Init(n *SpatialNet) error {
+	if h.BinSize <= 0 {
+		return errors.New("hashing layout needs a positive bin size")
+	}
+	n.ResetSpatialHashing(h.BinSize)
+	return nil
+}
+
+func (h *HashingLayout) Step(n *SpatialNet) {
+	n.SpringUpdateHashingParallel(h.SpringConstant,
+		h.StepSize,
+		h.Equilibrium,
+		h.Repulsion,
+		h.Friction,
+		h.BinSize,
+		h.EdgeWeights)
+}
+
+func (h *HashingLayout) Parameters() map[string]float32 {
+	params := h.SpringLayout.Parameters()
+	delete(params, "maxWorkers")
+	params["binSize"] = h.BinSize
+	return params
+}
+
+func (h *HashingLayout) SetParameter(name string, value float32) error {
+	switch name {
+	case "binSize":
+		h.BinSize = value
+	case "maxWorkers":
+		return unknownParameter(name)
+	default:
+		return h.SpringLayout.SetParameter(name, value)
+	}
+	return nil
+}
+
+// parallelFor calls body for every index in [0, count) using
+// at most maxWorkers go routines
+func parallelFor(count int, maxWorkers uint, body func(i int)) {
+	actualWorkers := max(min(maxWorkers, uint(count)), 1)
+
+	var wg = &sync.WaitGroup{}
+	queue := make(chan int, actualWorkers)
+
+	worker := func(wg *sync.WaitGroup, queue chan int) {
+		defer wg.Done()
+		for i := range queue {
+			body(i)
+		}
+	}
+
+	for range actualWorkers {
+		wg.Add(1)
+		go worker(wg, queue)
+	}
+	for i := range count {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,300 @@
+package networks
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// MultilevelLayout lays out very large networks in the style of Yifan Hu's
+// multilevel algorithm. The network is repeatedly coarsened by matching
+// neighbors, the coarsest network is laid out first, and positions are
+// prolonged back down level by level and refined with an inner layout.
+type MultilevelLayout struct {
+	//Name of the registered layout used at every level
+	InnerLayout string
+
+	//Steps of the inner layout run on each coarse level before moving
+	//to the next finer one. The original network is refined until the
+	//inner layout converges or the caller stops stepping.
+	LevelIters int
+
+	//Stop coarsening once a level has this many nodes or fewer
+	MinNodes int
+
+	//Size of the random offset given to nodes that are prolonged
+	//from the same coarse node, so they don't start on top of each other
+	Jitter float32
+
+	//holds the parameters of the inner layout
+	inner Layout
+
+	//levels[0] is the network being laid out, higher levels are coarser.
+	//parents[l][i] is the node in levels[l+1] that node i of levels[l]
+	//was collapsed into. csr is the view of levels[0] they were built from.
+	levels        []*SpatialNet
+	csr           *CSR
+	parents       [][]int
+	current       int
+	currentLayout Layout
+	itersAtLevel  int
+}
+
+func NewMultilevelLayout() *MultilevelLayout {
+	inner, _ := NewLayout("barneshut")
+	return &MultilevelLayout{InnerLayout: "barneshut",
+		LevelIters: 100,
+		MinNodes:   50,
+		Jitter:     1.0,
+		inner:      inner}
+}
+
+func init() {
+	RegisterLayout("multilevel", func() Layout { return NewMultilevelLayout() })
+}
+
+// SetInnerLayout changes the layout used to refine each level,
+// carrying over any parameters the two layouts share
+func (m *MultilevelLayout) SetInnerLayout(name string) error {
+	inner, err := NewLayout(name)
+	if err != nil {
+		return err
+	}
+	if m.inner != nil {
+		for param, value := range m.inner.Parameters() {
+			inner.SetParameter(param, value)
+		}
+	}
+	m.InnerLayout = name
+	m.inner = inner
+	return nil
+}
+
+// coarsen collapses each node of n with one of its neighbors.
+// Nodes are matched with their unmatched neighbor of lowest degree,
+// and nodes left without a match join a matched neighbor's group.
+// Returns the coarse network and the group of every node in n. The coarse
+// network is seeded from the generator of n, so seeded runs repeat.
+func coarsen(n *SpatialNet) (*SpatialNet, []int) {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return csr.Degree(order[a]) < csr.Degree(order[b])
+	})
+
+	group := make([]int, count)
+	for i := range group {
+		group[i] = -1
+	}
+	groups := 0
+	for _, u := range order {
+		if group[u] != -1 {
+			continue
+		}
+		best := -1
+		for _, v := range csr.NodeNeighbors(u) {
+			if v != u && group[v] == -1 && (best == -1 || csr.Degree(v) < csr.Degree(best)) {
+				best = v
+			}
+		}
+		if best != -1 {
+			group[u] = groups
+			group[best] = groups
+			groups++
+		}
+	}
+	for _, u := range order {
+		if group[u] != -1 {
+			continue
+		}
+		for _, v := range csr.NodeNeighbors(u) {
+			if group[v] != -1 {
+				group[u] = group[v]
+				break
+			}
+		}
+		if group[u] == -1 {
+			group[u] = groups
+			groups++
+		}
+	}
+
+	coarse := NewSpatialNet()
+	coarse.Seed(n.random().Int63())
+	for g := range groups {
+		coarse.AddNode(strconv.Itoa(g))
+	}
+	sizes := make([]int, groups)
+	for i, node := range n.NodeSlice {
+		g := group[i]
+		coarse.NodeSlice[g].X += node.X
+		coarse.NodeSlice[g].Y += node.Y
+		coarse.NodeSlice[g].Radius = max(coarse.NodeSlice[g].Radius, node.Radius)
+		sizes[g]++
+	}
+	for g := range groups {
+		coarse.NodeSlice[g].X /= float32(sizes[g])
+		coarse.NodeSlice[g].Y /= float32(sizes[g])
+	}
+	//coarse edges carry the total weight of the edges they replace
+	for i := range count {
+		weights := csr.NodeWeights(i)
+		for idx, j := range csr.NodeNeighbors(i) {
+			if i < j && group[i] != group[j] {
+				nameA := strconv.Itoa(group[i])
+				nameB := strconv.Itoa(group[j])
+				weight, _ := coarse.EdgeWeight(nameA, nameB)
+				coarse.AddWeightedEdge(nameA, nameB, weight+weights[idx])
+			}
+		}
+	}
+	return coarse, group
+}
+
+// newLevelLayout returns a copy of the inner layout. Layouts that can
+// keep the positions they start from are made to, so every level builds
+// on the positions prolonged from the coarser one.
+func (m *MultilevelLayout) newLevelLayout() (Layout, error) {
+	layout, err := NewLayout(m.InnerLayout)
+	if err != nil {
+		return nil, err
+	}
+	params := m.inner.Parameters()
+	for param, value := range params {
+		layout.SetParameter(param, value)
+	}
+	if _, exists := params["keepPositions"]; exists {
+		layout.SetParameter("keepPositions", 1.0)
+	}
+	return layout, nil
+}
+
+func (m *MultilevelLayout) Init(n *SpatialNet) error {
+	if m.inner == nil {
+		return errors.New("multilevel layout has no inner layout")
+	}
+	m.levels = []*SpatialNet{n}
+	m.parents = nil
+	m.csr = n.CSR()
+	for len(m.levels[len(m.levels)-1].NodeSlice) > m.MinNodes {
+		finer := m.levels[len(m.levels)-1]
+		coarse, group := coarsen(finer)
+		//stop once coarsening no longer shrinks the network,
+		//which happens when there are few edges left
+		if float32(len(coarse.NodeSlice)) > 0.9*float32(len(finer.NodeSlice)) {
+			break
+		}
+		m.levels = append(m.levels, coarse)
+		m.parents = append(m.parents, group)
+	}
+
+	m.current = len(m.levels) - 1
+	m.itersAtLevel = 0
+	var err error
+	m.currentLayout, err = m.newLevelLayout()
+	if err != nil {
+		return err
+	}
+	return m.currentLayout.Init(m.levels[m.current])
+}
+
+// prolong places every node of the next finer level around its coarse
+// node, scaling the layout up so the extra nodes have room to spread out
+func (m *MultilevelLayout) prolong() error {
+	coarse := m.levels[m.current]
+	fine := m.levels[m.current-1]
+	group := m.parents[m.current-1]
+	scale := float32(math.Sqrt(float64(len(fine.NodeSlice)) / float64(len(coarse.NodeSlice))))
+	rng := m.levels[0].random()
+	for i := range fine.NodeSlice {
+		node := &fine.NodeSlice[i]
+		parent := &coarse.NodeSlice[group[i]]
+		x := parent.X*scale + m.Jitter*(2*rng.Float32()-1)
+		y := parent.Y*scale + m.Jitter*(2*rng.Float32()-1)
+		node.Vx = 0.0
+		node.Vy = 0.0
+		if !node.Pinned {
+			node.X = x
+			node.Y = y
+			node.constrain()
+		}
+	}
+
+	m.current--
+	m.itersAtLevel = 0
+	var err error
+	m.currentLayout, err = m.newLevelLayout()
+	if err != nil {
+		return err
+	}
+	return m.currentLayout.Init(fine)
+}
+
+// Level returns the level currently being refined, 0 being the original network
+func (m *MultilevelLayout) Level() int {
+	return m.current
+}
+
+// ActiveNetwork returns the level currently being refined
+func (m *MultilevelLayout) ActiveNetwork(n *SpatialNet) *SpatialNet {
+	if len(m.levels) == 0 || m.levels[0] != n {
+		return n
+	}
+	return m.levels[m.current]
+}
+
+func (m *MultilevelLayout) Step(n *SpatialNet) {
+	if len(m.levels) == 0 || m.levels[0] != n || m.csr != n.CSR() {
+		if m.Init(n) != nil {
+			return
+		}
+	}
+	m.currentLayout.Step(m.levels[m.current])
+	m.itersAtLevel++
+	if m.current > 0 && (m.itersAtLevel >= m.LevelIters || m.currentLayout.Converged()) {
+		m.prolong()
+	}
+}
+
+func (m *MultilevelLayout) Converged() bool {
+	if m.currentLayout == nil || m.current > 0 {
+		return false
+	}
+	return m.currentLayout.Converged()
+}
+
+// Parameters returns the multilevel parameters along with
+// the parameters of the inner layout
+func (m *MultilevelLayout) Parameters() map[string]float32 {
+	params := make(map[string]float32)
+	if m.inner != nil {
+		params = m.inner.Parameters()
+	}
+	params["levelIters"] = float32(m.LevelIters)
+	params["minNodes"] = float32(m.MinNodes)
+	params["jitter"] = m.Jitter
+	return params
+}
+
+func (m *MultilevelLayout) SetParameter(name string, value float32) error {
+	switch name {
+	case "levelIters":
+		m.LevelIters = int(value)
+	case "minNodes":
+		m.MinNodes = int(value)
+	case "jitter":
+		m.Jitter = value
+	default:
+		if m.inner == nil {
+			return unknownParameter(name)
+		}
+		return m.inner.SetParameter(name, value)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package networks
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func multilevelPositions(t *testing.T, inner string) []SpatialNetNode {
+	t.Helper()
+	n := NewRandomSpatialNet(400, 0.01, rand.New(rand.NewSource(3)))
+	for i := range n.NodeSlice {
+		n.NodeSlice[i].X = n.Rng.Float32() * 100
+		n.NodeSlice[i].Y = n.Rng.Float32() * 100
+	}
+	m := NewMultilevelLayout()
+	err := m.SetInnerLayout(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.LevelIters = 5
+	runner := LayoutRunner{MaxIters: 60}
+	_, err = runner.Run(t.Context(), m, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.NodeSlice
+}
+
+func TestMultilevelSeededRunsRepeat(t *testing.T) {
+	for _, inner := range []string{"spring", "fruchtermanreingold"} {
+		first := multilevelPositions(t, inner)
+		second := multilevelPositions(t, inner)
+		for i := range first {
+			if first[i].X != second[i].X || first[i].Y != second[i].Y {
+				t.Fatalf("%s: node %s at (%v, %v), then at (%v, %v)", inner, first[i].Name,
+					first[i].X, first[i].Y, second[i].X, second[i].Y)
+			}
+		}
+	}
+}
+
+func TestMultilevelLevelsKeepPositions(t *testing.T) {
+	m := NewMultilevelLayout()
+	err := m.SetInnerLayout("fruchtermanreingold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetParameter("keepPositions", 0.0)
+	layout, err := m.newLevelLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Parameters()["keepPositions"] != 1.0 {
+		t.Error("levels of a multilevel layout scatter the prolonged positions")
+	}
+}
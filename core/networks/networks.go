@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"math/rand"
+	"slices"
 	"strconv"
 	"sync"
 )
@@ -11,13 +12,30 @@ import (
 type SpatialNetNode struct {
 	Name                 string
 	X, Y, Vx, Vy, Radius float32
+
+	//Pinned nodes keep their position in every layout
+	Pinned bool
+
+	//Limits on where layouts may move the node, nil for none
+	Constraint *Constraint
 }
 
 func (a *SpatialNetNode) Equals(b *SpatialNetNode) bool {
 	return a.Name == b.Name
 }
 
-type EdgeSet map[string]map[string]struct{}
+// EdgeSet maps the name of a node to the names of its
+// neighbors, and each neighbor to the weight of their edge
+type EdgeSet map[string]map[string]float32
+
+// WeightMode is how edge weights scale the attraction between neighbors
+type WeightMode int
+
+const (
+	WeightNone WeightMode = iota
+	WeightLinear
+	WeightLog
+)
 
 type SpatialNet struct {
 	//The slice pointing to the actual
@@ -28,33 +46,65 @@ type SpatialNet struct {
 	NodeIndeces map[string]uint
 
 	//Maps the name of a node to a slice containing the indeces
-	//of nodes it is adjacent to in the NodeSlice
+	//of nodes it is adjacent to in the NodeSlice.
+	//For directed networks these are the out-neighbors
 	Adjacencies EdgeSet
 
+	//Directed networks also keep the in-neighbors of each node,
+	//InAdjacencies is nil for undirected networks
+	Directed      bool
+	InAdjacencies EdgeSet
+
+	//Typed attributes of nodes, keyed by node name,
+	//and of edges, keyed by EdgeKey
+	NodeAttributes *AttributeTable[string]
+	EdgeAttributes *AttributeTable[EdgeKey]
+
+	//Source of randomness for layouts of this network,
+	//use Seed to make them reproducible
+	Rng *rand.Rand
+
 	//structures for spatial hashing
 	//SpatialBins map the int coords of a bin
 	//to a slice containing the indeces of that bin's
 	//nodes in NodeSlice
+	//SpatialAdjacencies map the int coords of a bin to the
+	//number of nodes in the bin each node is adjacent to
 	SpatialBins        map[[2]int][]uint
 	SpatialAdjacencies map[[2]int]map[string]int
+	spatialBinSize     float32
+	spatialCSR         *CSR
+
+	//bin each node is filed under, by index. Other layouts move nodes
+	//without refiling them, so a node may sit outside its bin until
+	//the next hashing step refiles it
+	nodeBins [][2]int
+
+	//index based view of Adjacencies, built on demand by CSR
+	csr *CSR
+
+	//copies of NodeSlice that are safe to read while a layout runs
+	positions positionBuffers
 }
 
 func NewSpatialNet() *SpatialNet {
 	return &SpatialNet{NodeSlice: make([]SpatialNetNode, 0),
-		NodeIndeces: make(map[string]uint),
-		Adjacencies: make(map[string]map[string]struct{})}
+		NodeIndeces:    make(map[string]uint),
+		Adjacencies:    make(EdgeSet),
+		NodeAttributes: NewAttributeTable[string](),
+		EdgeAttributes: NewAttributeTable[EdgeKey](),
+		Rng:            rand.New(rand.NewSource(rand.Int63()))}
 }
 
-func (n *SpatialNet) GetCOM() (float32, float32) {
-	var cx, cy float32
+func NewDirectedSpatialNet() *SpatialNet {
+	n := NewSpatialNet()
+	n.Directed = true
+	n.InAdjacencies = make(EdgeSet)
+	return n
+}
 
-	for _, node := range n.NodeSlice {
-		cx += node.X
-		cy += node.Y
-	}
-	cx /= float32(len(n.NodeSlice))
-	cy /= float32(len(n.NodeSlice))
-	return cx, cy
+func (n *SpatialNet) GetCOM() (float32, float32) {
+	return CenterOfMass(n.NodeSlice)
 }
 
 func (n *SpatialNet) ContainsNode(nodeName string) bool {
@@ -68,7 +118,8 @@ func (n *SpatialNet) ContainsEdge(nodeAName string, nodeBName string) bool {
 		return false
 	}
 
-	//grab the set of neighbors to node A, and check if node B exists in the set
+	//grab the set of neighbors to node A, and check if node B exists in the set.
+	//For directed networks this only finds edges from A to B
 	neighborSet := n.Adjacencies[nodeAName]
 	_, exists := neighborSet[nodeBName]
 	return exists
@@ -80,21 +131,222 @@ func (n *SpatialNet) AddNode(name string) error {
 	}
 	n.NodeSlice = append(n.NodeSlice, SpatialNetNode{Name: name})
 	n.NodeIndeces[name] = uint(len(n.NodeSlice) - 1)
-	n.Adjacencies[name] = make(map[string]struct{})
+	n.Adjacencies[name] = make(map[string]float32)
+	if n.Directed {
+		n.InAdjacencies[name] = make(map[string]float32)
+	}
+	n.csr = nil
 	return nil
 }
 
 func (n *SpatialNet) AddEdge(nameA, nameB string) error {
+	return n.AddWeightedEdge(nameA, nameB, 1.0)
+}
+
+// AddWeightedEdge adds an edge with the given weight, replacing
+// the weight if the edge already exists
+func (n *SpatialNet) AddWeightedEdge(nameA, nameB string, weight float32) error {
 	if !n.ContainsNode(nameA) || !n.ContainsNode(nameB) {
 		return errors.New("Cannot add edge between nodes that do not exist!")
 	}
-	n.Adjacencies[nameA][nameB] = struct{}{}
-	n.Adjacencies[nameB][nameA] = struct{}{}
+	if n.Directed {
+		n.Adjacencies[nameA][nameB] = weight
+		n.InAdjacencies[nameB][nameA] = weight
+	} else {
+		n.Adjacencies[nameA][nameB] = weight
+		n.Adjacencies[nameB][nameA] = weight
+	}
+	n.csr = nil
+	return nil
+}
+
+// EdgeWeight returns the weight of the edge from nameA to nameB,
+// and whether the edge exists
+func (n *SpatialNet) EdgeWeight(nameA, nameB string) (float32, bool) {
+	weight, exists := n.Adjacencies[nameA][nameB]
+	return weight, exists
+}
+
+// SetEdgeWeight changes the weight of an existing edge
+func (n *SpatialNet) SetEdgeWeight(nameA, nameB string, weight float32) error {
+	if !n.ContainsEdge(nameA, nameB) {
+		return errors.New("Cannot set the weight of an edge that does not exist!")
+	}
+	return n.AddWeightedEdge(nameA, nameB, weight)
+}
+
+// RemoveEdge removes the edge from nameA to nameB along with
+// its attributes. For undirected networks the order does not matter.
+func (n *SpatialNet) RemoveEdge(nameA, nameB string) error {
+	if !n.ContainsEdge(nameA, nameB) {
+		return errors.New("Cannot remove an edge that does not exist!")
+	}
+	hashing := n.spatialHashingCurrent()
+	n.removeEdge(nameA, nameB, hashing)
+	n.csr = nil
+	if hashing {
+		n.spatialCSR = n.CSR()
+	}
+	return nil
+}
+
+// removeEdge deletes an existing edge and updates the bin neighbor
+// counts if hashing is set. It leaves the index views to the caller,
+// so removing many edges doesn't rebuild them every time.
+func (n *SpatialNet) removeEdge(nameA, nameB string, hashing bool) {
+	delete(n.Adjacencies[nameA], nameB)
+	if n.Directed {
+		delete(n.InAdjacencies[nameB], nameA)
+	} else {
+		delete(n.Adjacencies[nameB], nameA)
+	}
+	n.EdgeAttributes.Delete(n.EdgeKey(nameA, nameB))
+
+	//nodes of a directed network may still be
+	//linked by the edge going the other way
+	if _, linked := n.Adjacencies[nameB][nameA]; hashing && !linked {
+		i := int(n.NodeIndeces[nameA])
+		j := int(n.NodeIndeces[nameB])
+		n.uncountBinNeighbor(n.nodeBins[i], nameB)
+		if i != j {
+			n.uncountBinNeighbor(n.nodeBins[j], nameA)
+		}
+	}
+}
+
+// RemoveNode removes a node along with its edges and attributes.
+// The last node in NodeSlice is moved into the freed index, so indeces
+// of other nodes held from before the removal may no longer be valid.
+func (n *SpatialNet) RemoveNode(name string) error {
+	if !n.ContainsNode(name) {
+		return errors.New("attempted to remove node named " + name + " which does not exist")
+	}
+	hashing := n.spatialHashingCurrent()
+	for nbr := range n.Adjacencies[name] {
+		n.removeEdge(name, nbr, hashing)
+	}
+	if n.Directed {
+		for nbr := range n.InAdjacencies[name] {
+			n.removeEdge(nbr, name, hashing)
+		}
+	}
+
+	i := int(n.NodeIndeces[name])
+	last := len(n.NodeSlice) - 1
+	if hashing {
+		//its edges were uncounted as they were removed
+		n.dropFromBin(i, n.nodeBins[i])
+	}
+	if i != last {
+		moved := n.NodeSlice[last]
+		n.NodeSlice[i] = moved
+		n.NodeIndeces[moved.Name] = uint(i)
+		if hashing {
+			binNodes := n.SpatialBins[n.nodeBins[last]]
+			for idx, j := range binNodes {
+				if int(j) == last {
+					binNodes[idx] = uint(i)
+					break
+				}
+			}
+			n.nodeBins[i] = n.nodeBins[last]
+		}
+	}
+	if hashing {
+		n.nodeBins = n.nodeBins[:last]
+	}
+	n.NodeSlice = n.NodeSlice[:last]
+	delete(n.NodeIndeces, name)
+	delete(n.Adjacencies, name)
+	if n.Directed {
+		delete(n.InAdjacencies, name)
+	}
+	n.NodeAttributes.Delete(name)
+	n.csr = nil
+	if hashing {
+		n.spatialCSR = n.CSR()
+	}
 	return nil
 }
 
-func NewRandomSpatialNet(numNodes int, edgeProb float32) *SpatialNet {
+// attractionScale returns how much harder than an unweighted
+// edge an edge of the given weight pulls its nodes together
+func (m WeightMode) attractionScale(weight float32) float32 {
+	switch m {
+	case WeightLinear:
+		return weight
+	case WeightLog:
+		return float32(math.Log1p(float64(weight)))
+	}
+	return 1.0
+}
+
+// ResetEdges removes every edge along with its attributes and
+// sets whether edges added from now on are directed
+func (n *SpatialNet) ResetEdges(directed bool) {
+	n.Directed = directed
+	n.Adjacencies = make(EdgeSet)
+	n.EdgeAttributes = NewAttributeTable[EdgeKey]()
+	n.InAdjacencies = nil
+	if directed {
+		n.InAdjacencies = make(EdgeSet)
+	}
+	for _, node := range n.NodeSlice {
+		n.Adjacencies[node.Name] = make(map[string]float32)
+		if directed {
+			n.InAdjacencies[node.Name] = make(map[string]float32)
+		}
+	}
+	n.csr = nil
+}
+
+// OutDegree returns the number of edges leaving a node,
+// which is its degree for undirected networks
+func (n *SpatialNet) OutDegree(name string) int {
+	return len(n.Adjacencies[name])
+}
+
+// InDegree returns the number of edges entering a node,
+// which is its degree for undirected networks
+func (n *SpatialNet) InDegree(name string) int {
+	if n.Directed {
+		return len(n.InAdjacencies[name])
+	}
+	return len(n.Adjacencies[name])
+}
+
+// Degree returns the number of distinct nodes a node shares an edge
+// with, in either direction
+func (n *SpatialNet) Degree(name string) int {
+	if !n.ContainsNode(name) {
+		return 0
+	}
+	return n.CSR().Degree(int(n.NodeIndeces[name]))
+}
+
+// Seed replaces the random number generator of the network with
+// one seeded with seed, so layouts started from here are reproducible
+func (n *SpatialNet) Seed(seed int64) {
+	n.Rng = rand.New(rand.NewSource(seed))
+}
+
+// random returns the generator of the network, creating one
+// for networks that were not built with NewSpatialNet
+func (n *SpatialNet) random() *rand.Rand {
+	if n.Rng == nil {
+		n.Rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	return n.Rng
+}
+
+// NewRandomSpatialNet returns an Erdos-Renyi random network drawn
+// with rng, which the network keeps as its generator.
+// A nil rng uses a randomly seeded one.
+func NewRandomSpatialNet(numNodes int, edgeProb float32, rng *rand.Rand) *SpatialNet {
 	n := NewSpatialNet()
+	if rng != nil {
+		n.Rng = rng
+	}
 
 	//add the nodes
 	for i := range numNodes {
@@ -104,7 +356,7 @@ func NewRandomSpatialNet(numNodes int, edgeProb float32) *SpatialNet {
 	//add the edges
 	for i := range numNodes {
 		for j := i + 1; j < numNodes; j++ {
-			if rand.Float32() < edgeProb {
+			if n.Rng.Float32() < edgeProb {
 				n.AddEdge(strconv.Itoa(i), strconv.Itoa(j))
 			}
 		}
@@ -113,42 +365,80 @@ func NewRandomSpatialNet(numNodes int, edgeProb float32) *SpatialNet {
 	return n
 }
 
+// repulsionForce returns the repulsion on node i from every other node
+func (n *SpatialNet) repulsionForce(i int, repulsion float32) (float32, float32) {
+	var fx, fy float32 = 0.0, 0.0
+	nodeA := &n.NodeSlice[i]
+	for j := range len(n.NodeSlice) {
+		if i == j {
+			continue
+		}
+		nodeB := &n.NodeSlice[j]
+		dx := nodeB.X - nodeA.X
+		dy := nodeB.Y - nodeA.Y
+		dist := float32(math.Hypot(float64(dx), float64(dy)))
+		if dist == 0 {
+			continue
+		}
+		clamped := max(dist, 1.0)
+		f := -1.0 * repulsion / (clamped * clamped)
+		fx += f * dx / dist
+		fy += f * dy / dist
+	}
+	return fx, fy
+}
+
+// neighborSpringForce returns the spring force on node i from the
+// given neighbors, with the spring scaled by the weight of each edge.
+// Neighbors feel the spring in place of repulsion, so the repulsion
+// already counted between them is taken back out.
+func (n *SpatialNet) neighborSpringForce(i int,
+	neighbors []int,
+	weights []float32,
+	k,
+	equilibriumDist,
+	repulsion float32,
+	weightMode WeightMode) (float32, float32) {
+
+	var fx, fy float32 = 0.0, 0.0
+	nodeA := &n.NodeSlice[i]
+	for idx, j := range neighbors {
+		if i == j {
+			continue
+		}
+		nodeB := &n.NodeSlice[j]
+		dx := nodeB.X - nodeA.X
+		dy := nodeB.Y - nodeA.Y
+		dist := float32(math.Hypot(float64(dx), float64(dy)))
+		if dist == 0 {
+			continue
+		}
+		clamped := max(dist, 1.0)
+		scale := weightMode.attractionScale(weights[idx])
+		f := (dist-equilibriumDist)*k*scale + repulsion/(clamped*clamped)
+		fx += f * dx / dist
+		fy += f * dy / dist
+	}
+	return fx, fy
+}
+
 func (n *SpatialNet) SpringUpdate(k,
 	stepSize,
 	equilibriumDist,
 	repulsion,
-	friction float32) {
+	friction float32,
+	weightMode WeightMode) {
 
+	csr := n.CSR()
 	for i := range len(n.NodeSlice) {
-		var fx, fy float32 = 0.0, 0.0
-		for j := range len(n.NodeSlice) {
-			if i == j {
-				continue
-			}
-			var f float32 = 0.0
-			nodeA := &n.NodeSlice[i]
-			nodeB := &n.NodeSlice[j]
-			dist := math.Hypot(float64(nodeB.X-nodeA.X), float64(nodeB.Y-nodeA.Y))
-			x := float32(dist) - equilibriumDist
-			if n.ContainsEdge(nodeA.Name, nodeB.Name) {
-				f = float32(x) * k
-			} else {
-				if dist < 1.0 {
-					dist = 1.0
-				}
-				f = -1.0 * repulsion / (float32(dist * dist))
-			}
-			theta := math.Atan2(float64(nodeB.Y-nodeA.Y), float64(nodeB.X-nodeA.X))
-			fx += f * float32(math.Cos(theta))
-			fy += f * float32(math.Sin(theta))
-		}
-		n.NodeSlice[i].Vx += stepSize * fx
-		n.NodeSlice[i].Vy += stepSize * fy
+		fx, fy := n.repulsionForce(i, repulsion)
+		sfx, sfy := n.neighborSpringForce(i, csr.NodeNeighbors(i), csr.NodeWeights(i), k, equilibriumDist, repulsion, weightMode)
+		n.NodeSlice[i].Vx += stepSize * (fx + sfx)
+		n.NodeSlice[i].Vy += stepSize * (fy + sfy)
 	}
 
 	for i := range len(n.NodeSlice) {
-		n.NodeSlice[i].X += stepSize * n.NodeSlice[i].Vx
-		n.NodeSlice[i].Y += stepSize * n.NodeSlice[i].Vy
+		n.NodeSlice[i].move(stepSize*n.NodeSlice[i].Vx, stepSize*n.NodeSlice[i].Vy)
 	}
 }
 
@@ -157,7 +447,8 @@ func (n *SpatialNet) SpringUpdateParallel(k,
 	equilibriumDist,
 	repulsion,
 	friction float32,
-	maxWorkers uint) {
+	maxWorkers uint,
+	weightMode WeightMode) {
 
 	actualWorkers := maxWorkers
 	if(len(n.NodeSlice) < int(actualWorkers)){
@@ -167,33 +458,14 @@ func (n *SpatialNet) SpringUpdateParallel(k,
 	var wg = &sync.WaitGroup{}
 	queue := make(chan int, actualWorkers)
 
+	csr := n.CSR()
 	worker := func(wg *sync.WaitGroup, queue chan int) {
 		defer wg.Done()
 		for i := range queue {
-			var fx, fy float32 = 0.0, 0.0
-			for j := range len(n.NodeSlice) {
-				if i == j {
-					continue
-				}
-				var f float32 = 0.0
-				nodeA := &n.NodeSlice[i]
-				nodeB := &n.NodeSlice[j]
-				dist := math.Hypot(float64(nodeB.X-nodeA.X), float64(nodeB.Y-nodeA.Y))
-				x := float32(dist) - equilibriumDist
-				if n.ContainsEdge(nodeA.Name, nodeB.Name) {
-					f = float32(x) * k
-				} else {
-					if dist < 1.0 {
-						dist = 1.0
-					}
-					f = -1.0 * repulsion / (float32(dist * dist))
-				}
-				theta := math.Atan2(float64(nodeB.Y-nodeA.Y), float64(nodeB.X-nodeA.X))
-				fx += f * float32(math.Cos(theta))
-				fy += f * float32(math.Sin(theta))
-			}
-			n.NodeSlice[i].Vx += stepSize * fx
-			n.NodeSlice[i].Vy += stepSize * fy
+			fx, fy := n.repulsionForce(i, repulsion)
+			sfx, sfy := n.neighborSpringForce(i, csr.NodeNeighbors(i), csr.NodeWeights(i), k, equilibriumDist, repulsion, weightMode)
+			n.NodeSlice[i].Vx += stepSize * (fx + sfx)
+			n.NodeSlice[i].Vy += stepSize * (fy + sfy)
 		}
 	}
 
@@ -209,8 +481,7 @@ func (n *SpatialNet) SpringUpdateParallel(k,
 
 	for i := range len(n.NodeSlice) {
 		wg.Go(func() {
-			n.NodeSlice[i].X += stepSize * n.NodeSlice[i].Vx
-			n.NodeSlice[i].Y += stepSize * n.NodeSlice[i].Vy
+			n.NodeSlice[i].move(stepSize*n.NodeSlice[i].Vx, stepSize*n.NodeSlice[i].Vy)
 			n.NodeSlice[i].Vx -= stepSize*friction*n.NodeSlice[i].Vx
 			n.NodeSlice[i].Vy -= stepSize*friction*n.NodeSlice[i].Vy
 		})
@@ -220,8 +491,8 @@ func (n *SpatialNet) SpringUpdateParallel(k,
 
 func (snn *SpatialNetNode) GetBin(binSize float32) [2]int {
 	var bin [2]int
-	bin[0] = int(snn.X / binSize)
-	bin[1] = int(snn.Y / binSize)
+	bin[0] = int(math.Floor(float64(snn.X / binSize)))
+	bin[1] = int(math.Floor(float64(snn.Y / binSize)))
 	return bin
 }
 
@@ -229,24 +500,203 @@ func (n *SpatialNet) ResetSpatialHashing(binSize float32) float32 {
 
 	n.SpatialBins = make(map[[2]int][]uint)
 	n.SpatialAdjacencies = make(map[[2]int]map[string]int)
+	n.nodeBins = make([][2]int, len(n.NodeSlice))
+	for i := range len(n.NodeSlice) {
+		n.addToBin(i, n.NodeSlice[i].GetBin(binSize))
+	}
+	n.spatialBinSize = binSize
+	n.spatialCSR = n.CSR()
+	return binSize
+}
+
+// addToBin places node i in bin and counts it as a
+// neighbor of every node it is adjacent to
+func (n *SpatialNet) addToBin(i int, bin [2]int) {
+	_, exists := n.SpatialBins[bin]
+	if !exists {
+		n.SpatialBins[bin] = make([]uint, 0)
+		n.SpatialAdjacencies[bin] = make(map[string]int)
+	}
+	n.SpatialBins[bin] = append(n.SpatialBins[bin], uint(i))
+	n.nodeBins[i] = bin
+	csr := n.CSR()
+	for _, j := range csr.NodeNeighbors(i) {
+		n.SpatialAdjacencies[bin][n.NodeSlice[j].Name]++
+	}
+}
+
+// removeFromBin undoes addToBin, dropping the bin once it is empty
+func (n *SpatialNet) removeFromBin(i int, bin [2]int) {
+	if !n.dropFromBin(i, bin) {
+		return
+	}
+	csr := n.CSR()
+	for _, j := range csr.NodeNeighbors(i) {
+		n.uncountBinNeighbor(bin, n.NodeSlice[j].Name)
+	}
+}
+
+// dropFromBin takes node i out of bin without touching the neighbor
+// counts, and reports whether the bin still holds any nodes
+func (n *SpatialNet) dropFromBin(i int, bin [2]int) bool {
+	binNodes := n.SpatialBins[bin]
+	for idx, j := range binNodes {
+		if int(j) == i {
+			binNodes[idx] = binNodes[len(binNodes)-1]
+			binNodes = binNodes[:len(binNodes)-1]
+			break
+		}
+	}
+	if len(binNodes) == 0 {
+		delete(n.SpatialBins, bin)
+		delete(n.SpatialAdjacencies, bin)
+		return false
+	}
+	n.SpatialBins[bin] = binNodes
+	return true
+}
+
+// uncountBinNeighbor undoes one count of name as
+// a neighbor of the nodes in bin
+func (n *SpatialNet) uncountBinNeighbor(bin [2]int, name string) {
+	counts, exists := n.SpatialAdjacencies[bin]
+	if !exists {
+		return
+	}
+	counts[name]--
+	if counts[name] <= 0 {
+		delete(counts, name)
+	}
+}
+
+// spatialHashingCurrent reports whether the bins were built
+// and match the nodes and edges of the network
+func (n *SpatialNet) spatialHashingCurrent() bool {
+	return n.SpatialBins != nil && n.spatialCSR != nil && n.spatialCSR == n.csr
+}
+
+// moveNodesHashing integrates the velocities of every node, applies
+// friction, and moves the nodes that are no longer in their bin
+func (n *SpatialNet) moveNodesHashing(stepSize, friction, binSize float32) {
 	for i := range len(n.NodeSlice) {
-		bin := n.NodeSlice[i].GetBin(binSize)
-		_, exists := n.SpatialBins[bin]
-		if !exists {
-			n.SpatialBins[bin] = make([]uint, 0)
+		node := &n.NodeSlice[i]
+		oldBin := n.nodeBins[i]
+		node.move(stepSize*node.Vx, stepSize*node.Vy)
+		node.Vx -= stepSize * friction * node.Vx
+		node.Vy -= stepSize * friction * node.Vy
+		newBin := node.GetBin(binSize)
+		if newBin != oldBin {
+			n.removeFromBin(i, oldBin)
+			n.addToBin(i, newBin)
 		}
-		_, exists = n.SpatialAdjacencies[bin]
-		if !exists {
-			n.SpatialAdjacencies[bin] = make(map[string]int)
-			n.SpatialAdjacencies[bin][n.NodeSlice[i].Name] = 0
+	}
+}
+
+// ensureSpatialHashing rebuilds the bins if they were never built,
+// were built with another bin size, or nodes or edges were added since
+func (n *SpatialNet) ensureSpatialHashing(binSize float32) {
+	if n.spatialBinSize != binSize || !n.spatialHashingCurrent() {
+		n.ResetSpatialHashing(binSize)
+	}
+}
+
+// localBinForce returns the exact force on node i from the other
+// nodes that share its bin
+func (n *SpatialNet) localBinForce(i int,
+	localBin [2]int,
+	csr *CSR,
+	k,
+	equilibriumDist,
+	repulsion,
+	binSize float32,
+	weightMode WeightMode) (float32, float32) {
+
+	var fx, fy float32 = 0.0, 0.0
+	nodeA := &n.NodeSlice[i]
+	for _, j := range n.SpatialBins[localBin] {
+		if i == int(j) {
+			continue
 		}
-		n.SpatialBins[bin] = append(n.SpatialBins[bin], uint(i))
-		neighborSet := n.Adjacencies[n.NodeSlice[i].Name]
-		for nbr, _ := range neighborSet {
-			n.SpatialAdjacencies[bin][nbr]++
+		nodeB := &n.NodeSlice[j]
+		dx := nodeB.X - nodeA.X
+		dy := nodeB.Y - nodeA.Y
+		dist := float32(math.Hypot(float64(dx), float64(dy)))
+		if dist == 0 {
+			continue
 		}
+		clamped := max(dist, 1.0)
+		f := -1.0 * repulsion / (clamped * clamped)
+		fx += f * dx / dist
+		fy += f * dy / dist
 	}
-	return binSize
+
+	//neighbors in other bins are accounted for by their bin
+	var localNeighbors []int
+	var localWeights []float32
+	weights := csr.NodeWeights(i)
+	for idx, j := range csr.NodeNeighbors(i) {
+		if n.nodeBins[j] == localBin {
+			localNeighbors = append(localNeighbors, j)
+			localWeights = append(localWeights, weights[idx])
+		}
+	}
+	sfx, sfy := n.neighborSpringForce(i, localNeighbors, localWeights, k, equilibriumDist, repulsion, weightMode)
+	return fx + sfx, fy + sfy
+}
+
+// sortedBins returns the coordinates of every occupied bin in a fixed
+// order, so forces are summed the same way on every run
+func (n *SpatialNet) sortedBins() [][2]int {
+	bins := make([][2]int, 0, len(n.SpatialBins))
+	for bin := range n.SpatialBins {
+		bins = append(bins, bin)
+	}
+	slices.SortFunc(bins, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	return bins
+}
+
+// otherBinsForce returns the approximate force on node i from every bin
+// other than its own, treating each bin as a point at its center.
+// Edges to other bins are counted, so their weights are not used.
+func (n *SpatialNet) otherBinsForce(i int,
+	bins [][2]int,
+	localBin [2]int,
+	k,
+	equilibriumDist,
+	repulsion,
+	binSize float32) (float32, float32) {
+
+	var fx, fy float32 = 0.0, 0.0
+	nodeA := &n.NodeSlice[i]
+	for _, bin := range bins {
+		if bin == localBin {
+			continue
+		}
+		binNodes := n.SpatialBins[bin]
+		binX := (float32(bin[0]) + 0.5) * binSize
+		binY := (float32(bin[1]) + 0.5) * binSize
+		dx := binX - nodeA.X
+		dy := binY - nodeA.Y
+		dist := float32(math.Hypot(float64(dx), float64(dy)))
+		if dist == 0 {
+			continue
+		}
+		clamped := max(dist, 1.0)
+
+		totalInBin := len(binNodes)
+		totalCon := n.SpatialAdjacencies[bin][nodeA.Name]
+		fCon := float32(totalCon) * (dist - equilibriumDist) * k
+		fDis := float32(totalInBin-totalCon) * (-1.0 * repulsion / (clamped * clamped))
+		f := fCon + fDis
+		fx += f * dx / dist
+		fy += f * dy / dist
+	}
+	return fx, fy
 }
 
 func (n *SpatialNet) SpringUpdateHashing(k,
@@ -254,67 +704,25 @@ func (n *SpatialNet) SpringUpdateHashing(k,
 	equilibriumDist,
 	repulsion,
 	friction,
-	binSize float32) {
+	binSize float32,
+	weightMode WeightMode) {
 
+	n.ensureSpatialHashing(binSize)
+	csr := n.CSR()
+	bins := n.sortedBins()
 	for i := range len(n.NodeSlice) {
-		var fx, fy float32 = 0.0, 0.0
-
 		//update with the nodes that are all within this bin
-		localBin := n.NodeSlice[i].GetBin(binSize)
-		localNodeIndeces := n.SpatialBins[localBin]
-		for j := range len(localNodeIndeces) {
-			var nbrIDX int = int(localNodeIndeces[j])
-			if i == nbrIDX {
-				continue
-			}
-			var f float32 = 0.0
-			nodeA := &n.NodeSlice[i]
-			nodeB := &n.NodeSlice[nbrIDX]
-			dist := math.Hypot(float64(nodeB.X-nodeA.X), float64(nodeB.Y-nodeA.Y))
-			x := float32(dist) - equilibriumDist
-			if n.ContainsEdge(nodeA.Name, nodeB.Name) {
-				f = float32(x) * k
-			} else {
-				if dist < 1.0 {
-					dist = 1.0
-				}
-				f = -1.0 * repulsion / (float32(dist * dist))
-			}
-			theta := math.Atan2(float64(nodeB.Y-nodeA.Y), float64(nodeB.X-nodeA.X))
-			fx += f * float32(math.Cos(theta))
-			fy += f * float32(math.Sin(theta))
-		}
+		localBin := n.nodeBins[i]
+		fx, fy := n.localBinForce(i, localBin, csr, k, equilibriumDist, repulsion, binSize, weightMode)
 
 		//update with other bins
-		for bin, _ := range n.SpatialBins {
-			if bin == localBin {
-				continue
-			}
-			var fDis, fCon float32 = 0.0, 0.0
-			nodeA := &n.NodeSlice[i]
-			binX := float32(bin[0]) * binSize
-			binY := float32(bin[1]) * binSize
-			dist := math.Hypot(float64(binX-nodeA.X), float64(binY-nodeA.Y))
-			x := float32(dist) - equilibriumDist
-
-			totalInBin := len(n.SpatialBins[bin])
-			totalCon := n.SpatialAdjacencies[bin][nodeA.Name]
-			fCon = float32(totalCon) * float32(x) * k
-			fDis = float32(totalInBin-totalCon) * (-1.0 * repulsion / (float32(dist * dist)))
-			theta := math.Atan2(float64(binY-nodeA.Y), float64(binX-nodeA.X))
-			f := fCon + fDis
-			fx += f * float32(math.Cos(theta))
-			fy += f * float32(math.Sin(theta))
-		}
+		bfx, bfy := n.otherBinsForce(i, bins, localBin, k, equilibriumDist, repulsion, binSize)
 
-		n.NodeSlice[i].Vx += stepSize * fx
-		n.NodeSlice[i].Vy += stepSize * fy
+		n.NodeSlice[i].Vx += stepSize * (fx + bfx)
+		n.NodeSlice[i].Vy += stepSize * (fy + bfy)
 	}
 
-	for i := range len(n.NodeSlice) {
-		n.NodeSlice[i].X += stepSize * n.NodeSlice[i].Vx
-		n.NodeSlice[i].Y += stepSize * n.NodeSlice[i].Vy
-	}
+	n.moveNodesHashing(stepSize, friction, binSize)
 }
 
 func (n *SpatialNet) SpringUpdateHashingParallel(k,
@@ -322,76 +730,28 @@ func (n *SpatialNet) SpringUpdateHashingParallel(k,
 	equilibriumDist,
 	repulsion,
 	friction,
-	binSize float32) {
+	binSize float32,
+	weightMode WeightMode) {
 
+	n.ensureSpatialHashing(binSize)
+	csr := n.CSR()
+	bins := n.sortedBins()
 	var wg sync.WaitGroup
 	for i := range len(n.NodeSlice) {
 		wg.Go(func() {
-			var fx, fy float32 = 0.0, 0.0
-
 			//update with the nodes that are all within this bin
-			localBin := n.NodeSlice[i].GetBin(binSize)
-			localNodeIndeces := n.SpatialBins[localBin]
-			for j := range len(localNodeIndeces) {
-				var nbrIDX int = int(localNodeIndeces[j])
-				if i == nbrIDX {
-					continue
-				}
-				var f float32 = 0.0
-				nodeA := &n.NodeSlice[i]
-				nodeB := &n.NodeSlice[nbrIDX]
-				dist := math.Hypot(float64(nodeB.X-nodeA.X), float64(nodeB.Y-nodeA.Y))
-				x := float32(dist) - equilibriumDist
-				if n.ContainsEdge(nodeA.Name, nodeB.Name) {
-					f = float32(x) * k
-				} else {
-					if dist < 1.0 {
-						dist = 1.0
-					}
-					f = -1.0 * repulsion / (float32(dist * dist))
-				}
-				theta := math.Atan2(float64(nodeB.Y-nodeA.Y), float64(nodeB.X-nodeA.X))
-				fx += f * float32(math.Cos(theta))
-				fy += f * float32(math.Sin(theta))
-			}
+			localBin := n.nodeBins[i]
+			fx, fy := n.localBinForce(i, localBin, csr, k, equilibriumDist, repulsion, binSize, weightMode)
 
 			//update with other bins
-			for bin, _ := range n.SpatialBins {
-				if bin == localBin {
-					continue
-				}
-				var fDis, fCon float32 = 0.0, 0.0
-				nodeA := &n.NodeSlice[i]
-				binX := float32(bin[0]) * binSize
-				binY := float32(bin[1]) * binSize
-				dist := math.Hypot(float64(binX-nodeA.X), float64(binY-nodeA.Y))
-				x := float32(dist) - equilibriumDist
-
-				totalInBin := len(n.SpatialBins[bin])
-				totalCon := n.SpatialAdjacencies[bin][nodeA.Name]
-				fCon = float32(totalCon) * float32(x) * k
-				fDis = float32(totalInBin-totalCon) * (-1.0 * repulsion / (float32(dist * dist)))
-				theta := math.Atan2(float64(binY-nodeA.Y), float64(binX-nodeA.X))
-				f := fCon + fDis
-				fx += f * float32(math.Cos(theta))
-				fy += f * float32(math.Sin(theta))
-			}
+			bfx, bfy := n.otherBinsForce(i, bins, localBin, k, equilibriumDist, repulsion, binSize)
 
-			n.NodeSlice[i].Vx += stepSize * fx
-			n.NodeSlice[i].Vy += stepSize * fy
+			n.NodeSlice[i].Vx += stepSize * (fx + bfx)
+			n.NodeSlice[i].Vy += stepSize * (fy + bfy)
 		})
 	}
 	wg.Wait()
 
-	//TODO: Nodes moving from bin to bin
-	// for i := range len(n.NodeSlice) {
-	// 	wg.Go(func() {
-	// 		oldBin := n.NodeSlice[i].GetBin()
-	// 		n.NodeSlice[i].X += stepSize * n.NodeSlice[i].Vx
-	// 		n.NodeSlice[i].Y += stepSize * n.NodeSlice[i].Vy
-	// 		newBin := n.NodeSlice[i].GetBin()
-	// 		n.SpatialBins[oldBin]
-	// 	})
-	// }
-	wg.Wait()
+	//moving nodes between bins edits the bin maps, so it stays serial
+	n.moveNodesHashing(stepSize, friction, binSize)
 }
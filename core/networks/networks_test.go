@@ -0,0 +1,260 @@
+package networks
+
+import (
+	"maps"
+	"math/rand"
+	"testing"
+)
+
+// checkSpatialHashing fails unless every node is filed in exactly one
+// bin, and the bins and neighbor counts match a fresh rebuild
+func checkSpatialHashing(t *testing.T, n *SpatialNet, binSize float32) {
+	t.Helper()
+	filed := make([]int, len(n.NodeSlice))
+	for _, binNodes := range n.SpatialBins {
+		for _, i := range binNodes {
+			if int(i) >= len(n.NodeSlice) {
+				t.Fatalf("bins hold index %d of %d nodes", i, len(n.NodeSlice))
+			}
+			filed[i]++
+		}
+	}
+	for i, count := range filed {
+		if count != 1 {
+			t.Fatalf("node %s is filed in %d bins", n.NodeSlice[i].Name, count)
+		}
+	}
+
+	got := make(map[[2]int]map[string]int)
+	for bin, counts := range n.SpatialAdjacencies {
+		got[bin] = make(map[string]int)
+		for name, c := range counts {
+			if c != 0 {
+				got[bin][name] = c
+			}
+		}
+	}
+	gotNodes := make(map[[2]int]int)
+	for bin, binNodes := range n.SpatialBins {
+		gotNodes[bin] = len(binNodes)
+	}
+	n.ResetSpatialHashing(binSize)
+	for bin, counts := range n.SpatialAdjacencies {
+		if !maps.Equal(got[bin], counts) {
+			t.Fatalf("bin %v counts neighbors %v, a rebuild counts %v", bin, got[bin], counts)
+		}
+		if gotNodes[bin] != len(n.SpatialBins[bin]) {
+			t.Fatalf("bin %v holds %d nodes, a rebuild holds %d", bin, gotNodes[bin], len(n.SpatialBins[bin]))
+		}
+	}
+	if len(got) != len(n.SpatialAdjacencies) {
+		t.Fatalf("%d bins, a rebuild has %d", len(got), len(n.SpatialAdjacencies))
+	}
+}
+
+func randomHashingNet(directed bool, seed int64) *SpatialNet {
+	n := NewSpatialNet()
+	if directed {
+		n = NewDirectedSpatialNet()
+	}
+	n.Seed(seed)
+	rng := rand.New(rand.NewSource(seed))
+	for i := range 80 {
+		n.AddNode(string(rune('A'+i/26)) + string(rune('a'+i%26)))
+		n.NodeSlice[i].X = rng.Float32()*400 - 200
+		n.NodeSlice[i].Y = rng.Float32()*400 - 200
+	}
+	for range 240 {
+		a := n.NodeSlice[rng.Intn(80)].Name
+		b := n.NodeSlice[rng.Intn(80)].Name
+		n.AddEdge(a, b)
+	}
+	return n
+}
+
+func TestSpatialHashingAfterOtherLayouts(t *testing.T) {
+	const binSize = 50.0
+	for _, directed := range []bool{false, true} {
+		n := randomHashingNet(directed, 1)
+		n.ResetSpatialHashing(binSize)
+		for range 20 {
+			n.SpringUpdateParallel(0.1, 0.5, 8.0, 800.0, 0.125, 4, WeightNone)
+		}
+		n.SpringUpdateBarnesHut(0.1, 0.5, 8.0, 800.0, 0.125, 0.8, 4, WeightNone)
+		n.SpringUpdateHashing(0.1, 0.1, 8.0, 80.0, 0.125, binSize, WeightNone)
+		checkSpatialHashing(t, n, binSize)
+	}
+}
+
+func TestRemoveWithSpatialHashing(t *testing.T) {
+	const binSize = 50.0
+	for _, directed := range []bool{false, true} {
+		n := randomHashingNet(directed, 2)
+		//self loops count their node as its own neighbor
+		for _, name := range []string{"Aa", "Ab", "Ac"} {
+			n.AddEdge(name, name)
+		}
+		n.ResetSpatialHashing(binSize)
+		//nodes leave their bins without being refiled
+		for range 20 {
+			n.SpringUpdateParallel(0.1, 0.5, 8.0, 800.0, 0.125, 4, WeightNone)
+		}
+		rng := rand.New(rand.NewSource(2))
+		for _, name := range []string{"Aa", "Ab"} {
+			err := n.RemoveEdge(name, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for range 30 {
+			err := n.RemoveNode(n.NodeSlice[rng.Intn(len(n.NodeSlice))].Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := n.NodeSlice[rng.Intn(len(n.NodeSlice))].Name
+			for b := range n.Adjacencies[a] {
+				n.RemoveEdge(a, b)
+				break
+			}
+		}
+		if !n.spatialHashingCurrent() {
+			t.Fatal("removals dropped the spatial hashing")
+		}
+		n.SpringUpdateHashing(0.1, 0.1, 8.0, 80.0, 0.125, binSize, WeightNone)
+		checkSpatialHashing(t, n, binSize)
+	}
+}
+
+func TestRemoveEdge(t *testing.T) {
+	tests := []struct {
+		name       string
+		directed   bool
+		remove     [2]string
+		kept, gone [][2]string
+	}{
+		{"undirected either order", false, [2]string{"b", "a"}, [][2]string{{"b", "c"}}, [][2]string{{"a", "b"}, {"b", "a"}}},
+		{"directed keeps reverse", true, [2]string{"a", "b"}, [][2]string{{"b", "a"}}, [][2]string{{"a", "b"}}},
+		{"self loop", false, [2]string{"c", "c"}, [][2]string{{"a", "b"}}, [][2]string{{"c", "c"}}},
+	}
+	for _, test := range tests {
+		n := NewSpatialNet()
+		if test.directed {
+			n = NewDirectedSpatialNet()
+		}
+		for _, name := range []string{"a", "b", "c"} {
+			n.AddNode(name)
+		}
+		n.AddEdge("a", "b")
+		n.AddEdge("b", "a")
+		n.AddEdge("b", "c")
+		n.AddEdge("c", "c")
+		err := n.RemoveEdge(test.remove[0], test.remove[1])
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for _, e := range test.kept {
+			if !n.ContainsEdge(e[0], e[1]) {
+				t.Errorf("%s: edge %s-%s was removed", test.name, e[0], e[1])
+			}
+		}
+		for _, e := range test.gone {
+			if n.ContainsEdge(e[0], e[1]) {
+				t.Errorf("%s: edge %s-%s was kept", test.name, e[0], e[1])
+			}
+		}
+		if n.RemoveEdge(test.remove[0], test.remove[1]) == nil {
+			t.Errorf("%s: removing the edge twice gave no error", test.name)
+		}
+	}
+}
+
+func TestRemoveNodeDirected(t *testing.T) {
+	n := NewDirectedSpatialNet()
+	for _, name := range []string{"a", "b", "c"} {
+		n.AddNode(name)
+	}
+	n.AddEdge("a", "b")
+	n.AddEdge("c", "b")
+	n.AddEdge("b", "b")
+	n.AddEdge("a", "c")
+	err := n.RemoveNode("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.NodeSlice) != 2 || n.ContainsNode("b") {
+		t.Fatalf("nodes left %v", n.NodeIndeces)
+	}
+	if len(n.Adjacencies["a"]) != 1 || len(n.InAdjacencies["c"]) != 1 || n.InDegree("a") != 0 {
+		t.Errorf("edges left %v, in edges %v", n.Adjacencies, n.InAdjacencies)
+	}
+	for name, i := range n.NodeIndeces {
+		if n.NodeSlice[i].Name != name {
+			t.Errorf("%s indexes node %s", name, n.NodeSlice[i].Name)
+		}
+	}
+}
+
+// twoSprings returns the edges a-b of weight 1 and c-d of weight heavy,
+// both stretched to the same length
+func twoSprings(heavy float32) *SpatialNet {
+	n := NewSpatialNet()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		n.AddNode(name)
+	}
+	n.AddWeightedEdge("a", "b", 1.0)
+	n.AddWeightedEdge("c", "d", heavy)
+	n.NodeSlice[1].X = 50.0
+	n.NodeSlice[2].Y = 100.0
+	n.NodeSlice[3].X, n.NodeSlice[3].Y = 50.0, 100.0
+	return n
+}
+
+func springLengths(n *SpatialNet) (float32, float32) {
+	return n.NodeSlice[1].X - n.NodeSlice[0].X, n.NodeSlice[3].X - n.NodeSlice[2].X
+}
+
+func TestSpringWeightModes(t *testing.T) {
+	tests := []struct {
+		mode    WeightMode
+		heavier bool
+	}{
+		{WeightNone, false},
+		{WeightLinear, true},
+		{WeightLog, true},
+	}
+	for _, test := range tests {
+		n := twoSprings(4.0)
+		n.SpringUpdate(0.01, 0.5, 8.0, 0.0, 0.125, test.mode)
+		light, heavy := springLengths(n)
+		if light >= 50.0 || heavy >= 50.0 {
+			t.Errorf("mode %d: springs did not contract, lengths %v and %v", test.mode, light, heavy)
+		}
+		if test.heavier && heavy >= light {
+			t.Errorf("mode %d: the heavy edge is %v long, the light one %v", test.mode, heavy, light)
+		}
+		if !test.heavier && heavy != light {
+			t.Errorf("mode %d: weights changed the lengths to %v and %v", test.mode, light, heavy)
+		}
+	}
+}
+
+func TestSpringWeightNoneMatchesUnweighted(t *testing.T) {
+	weighted := randomHashingNet(false, 3)
+	unweighted := randomHashingNet(false, 3)
+	for name, targets := range weighted.Adjacencies {
+		for nbr := range targets {
+			weighted.SetEdgeWeight(name, nbr, 7.5)
+		}
+	}
+	for range 10 {
+		weighted.SpringUpdateParallel(0.1, 0.1, 8.0, 80.0, 0.125, 4, WeightNone)
+		unweighted.SpringUpdateParallel(0.1, 0.1, 8.0, 80.0, 0.125, 4, WeightLinear)
+	}
+	for i := range weighted.NodeSlice {
+		a, b := weighted.NodeSlice[i], unweighted.NodeSlice[i]
+		if a.X != b.X || a.Y != b.Y {
+			t.Fatalf("node %s at (%v, %v) with weights ignored, (%v, %v) without weights",
+				a.Name, a.X, a.Y, b.X, b.Y)
+		}
+	}
+}
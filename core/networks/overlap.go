@@ -0,0 +1,100 @@
+package networks
+
+import "math"
+
+// Crowded layouts can't be untangled by local pushes alone, so every
+// overlapExpandEvery passes that still find overlaps the whole layout
+// is scaled up by overlapExpansion, which keeps its shape
+const (
+	overlapExpandEvery = 10
+	overlapExpansion   = 1.05
+)
+
+// RemoveOverlaps pushes apart nodes whose circles overlap, drawing every
+// node as a circle of Radius*radiusScale and keeping at least padding
+// between circles. Overlapping pairs move apart by half of their overlap
+// along the line between them, and crowded layouts are spread out evenly,
+// so the shape of the layout is kept. Pinned nodes stay put and
+// constraints are respected. Runs up to maxIters passes and returns
+// the number of overlapping pairs left.
+func (n *SpatialNet) RemoveOverlaps(radiusScale, padding float32, maxIters int) int {
+	var maxRadius float32
+	for i := range n.NodeSlice {
+		maxRadius = max(maxRadius, n.NodeSlice[i].Radius*radiusScale)
+	}
+	//nodes further apart than a cell can't overlap
+	cellSize := 2*maxRadius + padding
+	if len(n.NodeSlice) < 2 || cellSize <= 0 {
+		return 0
+	}
+	for iter := range maxIters {
+		if n.overlapPass(radiusScale, padding, cellSize, true) == 0 {
+			break
+		}
+		if iter%overlapExpandEvery == overlapExpandEvery-1 {
+			n.expand(overlapExpansion)
+		}
+	}
+	return n.overlapPass(radiusScale, padding, cellSize, false)
+}
+
+// overlapPass counts the overlapping pairs of nodes, found by binning
+// nodes into cells of cellSize, and pushes them apart if resolve is set
+func (n *SpatialNet) overlapPass(radiusScale, padding, cellSize float32, resolve bool) int {
+	cells := make(map[[2]int][]int)
+	for i := range n.NodeSlice {
+		cell := n.NodeSlice[i].GetBin(cellSize)
+		cells[cell] = append(cells[cell], i)
+	}
+	overlaps := 0
+	for i := range n.NodeSlice {
+		a := &n.NodeSlice[i]
+		cell := a.GetBin(cellSize)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range cells[[2]int{cell[0] + dx, cell[1] + dy}] {
+					if j <= i {
+						continue
+					}
+					b := &n.NodeSlice[j]
+					need := float64((a.Radius+b.Radius)*radiusScale + padding)
+					ox := float64(b.X - a.X)
+					oy := float64(b.Y - a.Y)
+					dist := math.Hypot(ox, oy)
+					if dist >= need {
+						continue
+					}
+					overlaps++
+					if !resolve || (a.Pinned && b.Pinned) {
+						continue
+					}
+					gap := need - dist
+					//nodes on top of each other part in a random direction
+					if dist < 1e-6 {
+						angle := 2 * math.Pi * n.random().Float64()
+						ox, oy, dist = math.Cos(angle), math.Sin(angle), 1.0
+					}
+					pushX, pushY := ox/dist*gap, oy/dist*gap
+					shareA, shareB := 0.5, 0.5
+					if a.Pinned {
+						shareA, shareB = 0.0, 1.0
+					} else if b.Pinned {
+						shareA, shareB = 1.0, 0.0
+					}
+					a.move(float32(-pushX*shareA), float32(-pushY*shareA))
+					b.move(float32(pushX*shareB), float32(pushY*shareB))
+				}
+			}
+		}
+	}
+	return overlaps
+}
+
+// expand scales the layout up about its center of mass
+func (n *SpatialNet) expand(factor float32) {
+	cx, cy := n.GetCOM()
+	for i := range n.NodeSlice {
+		node := &n.NodeSlice[i]
+		node.move((node.X-cx)*(factor-1), (node.Y-cy)*(factor-1))
+	}
+}
@@ -0,0 +1,237 @@
+package networks
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"slices"
+)
+
+// Path queries follow edge directions when the network is directed.
+// Unweighted queries count hops with a breadth first search, weighted
+// queries use Dijkstra's algorithm. Weights are tie strengths, as in the
+// spring layouts, so an edge is as long as the inverse of its weight and
+// paths prefer strong ties. Weights must be positive. Edges added without
+// a weight have length 1, making both agree on unweighted networks.
+
+// outEdges returns the out neighbors of every node and the
+// weights of those edges, by index
+func (n *SpatialNet) outEdges() ([][]int, [][]float64) {
+	count := len(n.NodeSlice)
+	targets := make([][]int, count)
+	weights := make([][]float64, count)
+	if n.Directed {
+		for i, node := range n.NodeSlice {
+			for nbr, weight := range n.Adjacencies[node.Name] {
+				targets[i] = append(targets[i], int(n.NodeIndeces[nbr]))
+				weights[i] = append(weights[i], float64(weight))
+			}
+		}
+		return targets, weights
+	}
+	csr := n.CSR()
+	for i := range count {
+		targets[i] = csr.NodeNeighbors(i)
+		for _, weight := range csr.NodeWeights(i) {
+			weights[i] = append(weights[i], float64(weight))
+		}
+	}
+	return targets, weights
+}
+
+// HasEdgeWeights reports whether any edge has a weight other than 1
+func (n *SpatialNet) HasEdgeWeights() bool {
+	for _, targets := range n.Adjacencies {
+		for _, weight := range targets {
+			if weight != 1.0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queuedNode is a node in a distanceQueue, with its distance when it
+// was queued. The distance is kept with the entry so lowering it later
+// doesn't break the heap, the stale entry is skipped when popped.
+type queuedNode struct {
+	node int
+	dist float64
+}
+
+// distanceQueue is a min-heap of nodes ordered by distance
+type distanceQueue []queuedNode
+
+func (q distanceQueue) Len() int           { return len(q) }
+func (q distanceQueue) Less(a, b int) bool { return q[a].dist < q[b].dist }
+func (q distanceQueue) Swap(a, b int)      { q[a], q[b] = q[b], q[a] }
+func (q *distanceQueue) Push(x any)        { *q = append(*q, x.(queuedNode)) }
+func (q *distanceQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// shortestPathTree returns the distance from source to every node,
+// infinite if unreachable, and the predecessors of every node on
+// all of its shortest paths. The search stops once target is settled,
+// pass -1 to reach every node.
+func (n *SpatialNet) shortestPathTree(source, target int, weighted bool) ([]float64, [][]int, error) {
+	count := len(n.NodeSlice)
+	targets, weights := n.outEdges()
+	dist := make([]float64, count)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	preds := make([][]int, count)
+	dist[source] = 0
+
+	if !weighted {
+		queue := []int{source}
+		for head := 0; head < len(queue); head++ {
+			current := queue[head]
+			if current == target {
+				break
+			}
+			for _, nbr := range targets[current] {
+				if math.IsInf(dist[nbr], 1) {
+					dist[nbr] = dist[current] + 1
+					queue = append(queue, nbr)
+				}
+				if dist[nbr] == dist[current]+1 {
+					preds[nbr] = append(preds[nbr], current)
+				}
+			}
+		}
+		return dist, preds, nil
+	}
+
+	for i := range weights {
+		for idx, weight := range weights[i] {
+			if weight <= 0 {
+				return nil, nil, errors.New("weighted shortest paths need edge weights > 0")
+			}
+			weights[i][idx] = 1.0 / weight
+		}
+	}
+	settled := make([]bool, count)
+	queue := &distanceQueue{{source, 0}}
+	for queue.Len() > 0 {
+		entry := heap.Pop(queue).(queuedNode)
+		current := entry.node
+		if settled[current] || entry.dist > dist[current] {
+			continue
+		}
+		settled[current] = true
+		if current == target {
+			break
+		}
+		for idx, nbr := range targets[current] {
+			if settled[nbr] {
+				continue
+			}
+			candidate := dist[current] + weights[current][idx]
+			//sums of float weights along different paths rarely match exactly
+			tie := 1e-9 * max(1.0, candidate)
+			switch {
+			case candidate < dist[nbr]-tie:
+				dist[nbr] = candidate
+				preds[nbr] = append(preds[nbr][:0], current)
+				heap.Push(queue, queuedNode{nbr, candidate})
+			case candidate <= dist[nbr]+tie:
+				preds[nbr] = append(preds[nbr], current)
+			}
+		}
+	}
+	return dist, preds, nil
+}
+
+// pathSearch returns the indeces of source and target, and the
+// shortest path tree from source searched until target is reached
+func (n *SpatialNet) pathSearch(source, target string, weighted bool) (int, int, []float64, [][]int, error) {
+	if !n.ContainsNode(source) {
+		return 0, 0, nil, nil, errors.New("node " + source + " does not exist")
+	}
+	if !n.ContainsNode(target) {
+		return 0, 0, nil, nil, errors.New("node " + target + " does not exist")
+	}
+	s := int(n.NodeIndeces[source])
+	t := int(n.NodeIndeces[target])
+	dist, preds, err := n.shortestPathTree(s, t, weighted)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+	if math.IsInf(dist[t], 1) {
+		return 0, 0, nil, nil, errors.New("no path from " + source + " to " + target)
+	}
+	return s, t, dist, preds, nil
+}
+
+// ShortestPath returns the names of the nodes on a shortest path from
+// source to target, both included, and its length in hops, or the sum
+// of the inverse edge weights when weighted is set
+func (n *SpatialNet) ShortestPath(source, target string, weighted bool) ([]string, float32, error) {
+	s, t, dist, preds, err := n.pathSearch(source, target, weighted)
+	if err != nil {
+		return nil, 0, err
+	}
+	path := []string{n.NodeSlice[t].Name}
+	for current := t; current != s; {
+		current = preds[current][0]
+		path = append(path, n.NodeSlice[current].Name)
+	}
+	slices.Reverse(path)
+	return path, float32(dist[t]), nil
+}
+
+// AllShortestPaths returns every shortest path from source to target and
+// their length. The number of paths can grow exponentially with their
+// length on dense networks such as lattices.
+func (n *SpatialNet) AllShortestPaths(source, target string, weighted bool) ([][]string, float32, error) {
+	s, t, dist, preds, err := n.pathSearch(source, target, weighted)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	//walk back from target through every predecessor
+	var paths [][]string
+	stack := []int{t}
+	var walk func(current int)
+	walk = func(current int) {
+		if current == s {
+			path := make([]string, len(stack))
+			for idx, i := range stack {
+				path[len(stack)-1-idx] = n.NodeSlice[i].Name
+			}
+			paths = append(paths, path)
+			return
+		}
+		for _, pred := range preds[current] {
+			stack = append(stack, pred)
+			walk(pred)
+			stack = stack[:len(stack)-1]
+		}
+	}
+	walk(t)
+	return paths, float32(dist[t]), nil
+}
+
+// ShortestPathLengths returns the length of the shortest path from
+// source to every node it can reach
+func (n *SpatialNet) ShortestPathLengths(source string, weighted bool) (map[string]float32, error) {
+	if !n.ContainsNode(source) {
+		return nil, errors.New("node " + source + " does not exist")
+	}
+	dist, _, err := n.shortestPathTree(int(n.NodeIndeces[source]), -1, weighted)
+	if err != nil {
+		return nil, err
+	}
+	lengths := make(map[string]float32)
+	for i, d := range dist {
+		if !math.IsInf(d, 1) {
+			lengths[n.NodeSlice[i].Name] = float32(d)
+		}
+	}
+	return lengths, nil
+}
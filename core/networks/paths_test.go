@@ -0,0 +1,99 @@
+package networks
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestWeightedShortestPathFollowsStrongTies(t *testing.T) {
+	tests := []struct {
+		name     string
+		direct   float32
+		weighted bool
+		want     []string
+		length   float32
+	}{
+		//a weak direct tie is longer than two ordinary ones
+		{"weak tie", 0.1, true, []string{"a", "b", "c"}, 2.0},
+		{"strong tie", 4.0, true, []string{"a", "c"}, 0.25},
+		{"hops", 0.1, false, []string{"a", "c"}, 1.0},
+	}
+	for _, test := range tests {
+		n := edgeListNet(t, []weightedEdge{{"a", "b", 1.0}, {"b", "c", 1.0}, {"a", "c", test.direct}})
+		path, length, err := n.ShortestPath("a", "c", test.weighted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(path, test.want) || math.Abs(float64(length-test.length)) > 1e-6 {
+			t.Errorf("%s: path %v of length %v, want %v of length %v",
+				test.name, path, length, test.want, test.length)
+		}
+	}
+}
+
+func TestWeightedShortestPathNeedsPositiveWeights(t *testing.T) {
+	n := edgeListNet(t, []weightedEdge{{"a", "b", 1.0}, {"b", "c", 0.0}})
+	if _, _, err := n.ShortestPath("a", "c", true); err == nil {
+		t.Error("a zero weight edge was accepted")
+	}
+}
+
+// bellmanFord returns the weighted distance from source to every node,
+// relaxing every edge until nothing changes
+func bellmanFord(n *SpatialNet, source int) []float64 {
+	dist := make([]float64, len(n.NodeSlice))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	dist[source] = 0
+	for changed := true; changed; {
+		changed = false
+		for a, targets := range n.Adjacencies {
+			i := n.NodeIndeces[a]
+			for b, weight := range targets {
+				j := n.NodeIndeces[b]
+				if candidate := dist[i] + 1.0/float64(weight); candidate < dist[j] {
+					dist[j] = candidate
+					changed = true
+				}
+			}
+		}
+	}
+	return dist
+}
+
+func TestWeightedDistancesMatchBellmanFord(t *testing.T) {
+	for _, directed := range []bool{false, true} {
+		for seed := range int64(10) {
+			n := NewSpatialNet()
+			if directed {
+				n = NewDirectedSpatialNet()
+			}
+			rng := rand.New(rand.NewSource(seed))
+			for i := range 100 {
+				n.AddNode(strconv.Itoa(i))
+			}
+			for range 600 {
+				a := n.NodeSlice[rng.Intn(100)].Name
+				b := n.NodeSlice[rng.Intn(100)].Name
+				n.AddWeightedEdge(a, b, 0.01+rng.Float32()*50)
+			}
+			for source := range n.NodeSlice {
+				got, _, err := n.shortestPathTree(source, -1, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := bellmanFord(n, source)
+				for i := range want {
+					if got[i] != want[i] && math.Abs(got[i]-want[i]) > 1e-9*max(1.0, want[i]) {
+						t.Fatalf("directed %v seed %d: distance from %d to %d is %v, want %v",
+							directed, seed, source, i, got[i], want[i])
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package networks
+
+import "sync"
+
+// Layouts move the nodes in NodeSlice from their own go routine, so
+// anything drawing the network while a layout runs must not read
+// NodeSlice. Instead the layout go routine publishes the positions
+// after every step with PublishPositions, and readers take a copy of
+// the last published positions with ReadPositions.
+
+// positionBuffers double buffers NodeSlice. The publisher fills back
+// without holding the lock and swaps it to the front, readers only
+// ever see front.
+type positionBuffers struct {
+	mu          sync.RWMutex
+	front, back []SpatialNetNode
+}
+
+// PublishPositions makes the current nodes, with their positions, the
+// ones returned by ReadPositions. Only one go routine may publish at a
+// time: the one running the layout, or any while no layout is running.
+func (n *SpatialNet) PublishPositions() {
+	back := append(n.positions.back[:0], n.NodeSlice...)
+	n.positions.mu.Lock()
+	n.positions.front, n.positions.back = back, n.positions.front
+	n.positions.mu.Unlock()
+}
+
+// ReadPositions copies the last published nodes into dst, reusing its
+// storage, and returns it. The copy is a consistent frame and is safe
+// to read while a layout runs. Indeces match NodeSlice as long as no
+// nodes are added or removed since they were published.
+func (n *SpatialNet) ReadPositions(dst []SpatialNetNode) []SpatialNetNode {
+	n.positions.mu.RLock()
+	defer n.positions.mu.RUnlock()
+	return append(dst[:0], n.positions.front...)
+}
+
+// CenterOfMass returns the mean position of nodes
+func CenterOfMass(nodes []SpatialNetNode) (float32, float32) {
+	var cx, cy float32
+	for _, node := range nodes {
+		cx += node.X
+		cy += node.Y
+	}
+	cx /= float32(len(nodes))
+	cy /= float32(len(nodes))
+	return cx, cy
+}
@@ -0,0 +1,86 @@
+package networks
+
+import (
+	"context"
+	"time"
+)
+
+// LayoutProgress is passed to a LayoutObserver after every step
+type LayoutProgress struct {
+	StepStats
+
+	//Time since the run started, including Init
+	Elapsed time.Duration
+}
+
+// LayoutObserver is called from the go routine running the layout,
+// so it must not block for long or touch NodeSlice of another network
+type LayoutObserver func(progress LayoutProgress)
+
+// LayoutResult describes how a run ended. Runs that were cancelled
+// leave the nodes where the last finished step put them.
+type LayoutResult struct {
+	Iterations int
+	Converged  bool
+	Cancelled  bool
+
+	//Stats of the last step, zero if no step ran
+	Last StepStats
+
+	Elapsed time.Duration
+}
+
+// LayoutRunner initializes a layout and steps it until it converges,
+// runs MaxIters steps or its context is cancelled. Positions are
+// published after Init and after every step, see PublishPositions,
+// so the network can be drawn while the runner works.
+type LayoutRunner struct {
+	MaxIters int
+
+	//Decides when the layout has converged, without
+	//one the layout runs until it says it has converged
+	Monitor *ConvergenceMonitor
+
+	//Called after every step, may be nil
+	Observer LayoutObserver
+}
+
+// Run lays out n. Cancelling ctx stops the run between steps, which
+// is reported in the result rather than as an error. The only errors
+// are those from initializing the layout.
+func (r *LayoutRunner) Run(ctx context.Context, layout Layout, n *SpatialNet) (LayoutResult, error) {
+	start := time.Now()
+	monitor := r.Monitor
+	if monitor == nil {
+		monitor = NewConvergenceMonitor(0.0, 1)
+	}
+	monitor.Reset()
+
+	//build the shared index view before anything can read it concurrently
+	n.CSR()
+	err := layout.Init(n)
+	if err != nil {
+		return LayoutResult{Elapsed: time.Since(start)}, err
+	}
+	n.PublishPositions()
+
+	var result LayoutResult
+	for range r.MaxIters {
+		if ctx.Err() != nil {
+			result.Cancelled = true
+			break
+		}
+		result.Last = monitor.Step(layout, n)
+		result.Iterations++
+		n.PublishPositions()
+		if r.Observer != nil {
+			r.Observer(LayoutProgress{StepStats: result.Last, Elapsed: time.Since(start)})
+		}
+		if monitor.Converged() {
+			result.Converged = true
+			break
+		}
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
@@ -0,0 +1,304 @@
+package networks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// NetworkStats summarizes the structure of a network. Degrees count
+// distinct neighbors, following edges of directed networks both ways
+// and ignoring self loops, and so do clustering, assortativity,
+// triangles and the diameter. Use String to print it as a table, or
+// encoding/json to serialize it.
+type NetworkStats struct {
+	Nodes    int  `json:"nodes"`
+	Edges    int  `json:"edges"`
+	Directed bool `json:"directed"`
+
+	//Fraction of the possible edges, counting both directions
+	//as separate edges for directed networks
+	Density float64 `json:"density"`
+
+	Components int `json:"components"`
+
+	MinDegree     int     `json:"min_degree"`
+	MaxDegree     int     `json:"max_degree"`
+	AverageDegree float64 `json:"average_degree"`
+
+	//Number of nodes of every degree, indexed by degree
+	DegreeHistogram []int `json:"degree_histogram"`
+
+	//Mean of the local clustering coefficients, where nodes
+	//with fewer than two neighbors count as 0
+	AverageClustering float64 `json:"average_clustering"`
+
+	//Fraction of connected triples that are closed into triangles
+	GlobalClustering float64 `json:"global_clustering"`
+
+	Triangles int `json:"triangles"`
+
+	//Correlation between the degrees at either end of an edge,
+	//0 when every edge joins nodes of the same degree
+	Assortativity float64 `json:"assortativity"`
+
+	//Longest shortest path in hops within any component. When
+	//DiameterExact is false it is a lower bound from a few sweeps.
+	Diameter      int  `json:"diameter"`
+	DiameterExact bool `json:"diameter_exact"`
+}
+
+// diameterSearches limits the breadth first searches an
+// approximate diameter runs in every component
+const diameterSearches = 8
+
+// Stats computes a NetworkStats. An exact diameter runs a breadth first
+// search from every node, split across maxWorkers go routines, which is
+// slow past ~10k nodes, otherwise it is estimated with a few searches
+// per component that are usually exact on real networks.
+func (n *SpatialNet) Stats(exactDiameter bool, maxWorkers uint) NetworkStats {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+	stats := NetworkStats{Nodes: count, Directed: n.Directed}
+
+	for source, targets := range n.Adjacencies {
+		for target := range targets {
+			if n.Directed || source <= target {
+				stats.Edges++
+			}
+		}
+	}
+	if count > 1 {
+		pairs := float64(count) * float64(count-1)
+		if !n.Directed {
+			pairs /= 2
+		}
+		stats.Density = float64(stats.Edges) / pairs
+	}
+	stats.Components = len(n.ConnectedComponents())
+
+	//neighbors of every node without self loops
+	neighbors := make([][]int, count)
+	degree := make([]int, count)
+	for i := range count {
+		for _, nbr := range csr.NodeNeighbors(i) {
+			if nbr != i {
+				neighbors[i] = append(neighbors[i], nbr)
+			}
+		}
+		degree[i] = len(neighbors[i])
+	}
+	if count > 0 {
+		stats.MinDegree = degree[0]
+	}
+	totalDegree := 0
+	for _, d := range degree {
+		stats.MinDegree = min(stats.MinDegree, d)
+		stats.MaxDegree = max(stats.MaxDegree, d)
+		totalDegree += d
+	}
+	stats.DegreeHistogram = make([]int, stats.MaxDegree+1)
+	for _, d := range degree {
+		stats.DegreeHistogram[d]++
+	}
+	if count > 0 {
+		stats.AverageDegree = float64(totalDegree) / float64(count)
+	}
+
+	triangles := n.triangleCounts(neighbors)
+	closed, triples := 0.0, 0.0
+	for i, t := range triangles {
+		stats.Triangles += t
+		d := float64(degree[i])
+		if d > 1 {
+			stats.AverageClustering += 2 * float64(t) / (d * (d - 1))
+		}
+		closed += float64(t)
+		triples += d * (d - 1) / 2
+	}
+	stats.Triangles /= 3
+	if count > 0 {
+		stats.AverageClustering /= float64(count)
+	}
+	if triples > 0 {
+		stats.GlobalClustering = closed / triples
+	}
+
+	stats.Assortativity = degreeAssortativity(neighbors, degree)
+	if exactDiameter {
+		stats.Diameter = n.exactDiameter(neighbors, maxWorkers)
+		stats.DiameterExact = true
+	} else {
+		stats.Diameter = n.approximateDiameter(neighbors, degree)
+	}
+	return stats
+}
+
+// triangleCounts returns the number of triangles every node is part of
+func (n *SpatialNet) triangleCounts(neighbors [][]int) []int {
+	count := len(neighbors)
+	triangles := make([]int, count)
+	marked := make([]bool, count)
+	for i := range count {
+		for _, j := range neighbors[i] {
+			marked[j] = true
+		}
+		//count each triangle once, from its lowest node
+		for _, j := range neighbors[i] {
+			if j < i {
+				continue
+			}
+			for _, k := range neighbors[j] {
+				if k > j && marked[k] {
+					triangles[i]++
+					triangles[j]++
+					triangles[k]++
+				}
+			}
+		}
+		for _, j := range neighbors[i] {
+			marked[j] = false
+		}
+	}
+	return triangles
+}
+
+// degreeAssortativity returns the Pearson correlation of the
+// degrees at the two ends of every edge, taken in both directions
+func degreeAssortativity(neighbors [][]int, degree []int) float64 {
+	var sumX, sumXX, sumXY, ends float64
+	for i := range neighbors {
+		x := float64(degree[i])
+		for _, j := range neighbors[i] {
+			sumX += x
+			sumXX += x * x
+			sumXY += x * float64(degree[j])
+			ends++
+		}
+	}
+	if ends == 0 {
+		return 0.0
+	}
+	mean := sumX / ends
+	variance := sumXX/ends - mean*mean
+	if variance <= 1e-12 {
+		return 0.0
+	}
+	return (sumXY/ends - mean*mean) / variance
+}
+
+// eccentricity returns the largest hop distance from source to a node
+// it can reach, and that node. dist must be all -1 and is left that way,
+// so searches in small components don't pay for the whole network.
+func eccentricity(neighbors [][]int, source int, dist []int) (int, int) {
+	dist[source] = 0
+	queue := []int{source}
+	farthest := source
+	for head := 0; head < len(queue); head++ {
+		current := queue[head]
+		farthest = current
+		for _, nbr := range neighbors[current] {
+			if dist[nbr] == -1 {
+				dist[nbr] = dist[current] + 1
+				queue = append(queue, nbr)
+			}
+		}
+	}
+	ecc := dist[farthest]
+	for _, i := range queue {
+		dist[i] = -1
+	}
+	return ecc, farthest
+}
+
+func (n *SpatialNet) exactDiameter(neighbors [][]int, maxWorkers uint) int {
+	count := len(neighbors)
+	workers := int(max(min(maxWorkers, uint(count)), 1))
+	partial := make([]int, workers)
+	parallelFor(workers, uint(workers), func(w int) {
+		dist := make([]int, count)
+		for i := range dist {
+			dist[i] = -1
+		}
+		for source := w; source < count; source += workers {
+			ecc, _ := eccentricity(neighbors, source, dist)
+			partial[w] = max(partial[w], ecc)
+		}
+	})
+	diameter := 0
+	for _, d := range partial {
+		diameter = max(diameter, d)
+	}
+	return diameter
+}
+
+// approximateDiameter runs repeated sweeps in every component: starting
+// at its highest degree node, each search starts from the farthest node
+// found by the last, until the distance found stops growing. On most
+// networks this reaches the ends of a longest shortest path.
+func (n *SpatialNet) approximateDiameter(neighbors [][]int, degree []int) int {
+	dist := make([]int, len(neighbors))
+	for i := range dist {
+		dist[i] = -1
+	}
+	diameter := 0
+	for _, members := range n.ConnectedComponents() {
+		start := members[0]
+		for _, i := range members {
+			if degree[i] > degree[start] {
+				start = i
+			}
+		}
+		last := -1
+		for range diameterSearches {
+			ecc, far := eccentricity(neighbors, start, dist)
+			if ecc <= last {
+				break
+			}
+			diameter = max(diameter, ecc)
+			last = ecc
+			start = far
+		}
+	}
+	return diameter
+}
+
+// String formats the stats as a table, listing only
+// the degrees that some node has in the histogram
+func (s NetworkStats) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	row := func(name string, value any) {
+		fmt.Fprintf(w, "%s\t%v\n", name, value)
+	}
+	float := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', 6, 64)
+	}
+	row("Nodes", s.Nodes)
+	row("Edges", s.Edges)
+	row("Directed", s.Directed)
+	row("Density", float(s.Density))
+	row("Components", s.Components)
+	row("Min degree", s.MinDegree)
+	row("Max degree", s.MaxDegree)
+	row("Average degree", float(s.AverageDegree))
+	row("Average clustering", float(s.AverageClustering))
+	row("Global clustering", float(s.GlobalClustering))
+	row("Triangles", s.Triangles)
+	row("Assortativity", float(s.Assortativity))
+	diameter := strconv.Itoa(s.Diameter)
+	if !s.DiameterExact {
+		diameter += " (approximate)"
+	}
+	row("Diameter", diameter)
+	fmt.Fprintln(w, "\t")
+	fmt.Fprintln(w, "Degree\tNodes")
+	for d, nodes := range s.DegreeHistogram {
+		if nodes > 0 {
+			row(strconv.Itoa(d), nodes)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
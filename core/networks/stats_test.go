@@ -0,0 +1,93 @@
+package networks
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	//the diamond is K4 without the edge c-d
+	diamond := []weightedEdge{{"a", "b", 1.0}, {"a", "c", 1.0}, {"a", "d", 1.0},
+		{"b", "c", 1.0}, {"b", "d", 1.0}}
+	path := []weightedEdge{{"a", "b", 1.0}, {"b", "c", 1.0}, {"c", "d", 1.0}}
+
+	tests := []struct {
+		name  string
+		edges []weightedEdge
+		want  NetworkStats
+	}{
+		{"K4", cliqueEdges("a", "b", "c", "d"), NetworkStats{Nodes: 4, Edges: 6,
+			Density: 1.0, Components: 1, MinDegree: 3, MaxDegree: 3, AverageDegree: 3.0,
+			DegreeHistogram: []int{0, 0, 0, 4}, AverageClustering: 1.0,
+			GlobalClustering: 1.0, Triangles: 4, Assortativity: 0.0, Diameter: 1}},
+		//a and b close both triangles of their 3 pairs of neighbors,
+		//c and d the only one of theirs
+		{"diamond", diamond, NetworkStats{Nodes: 4, Edges: 5,
+			Density: 5.0 / 6.0, Components: 1, MinDegree: 2, MaxDegree: 3, AverageDegree: 2.5,
+			DegreeHistogram: []int{0, 0, 2, 2}, AverageClustering: (2.0/3.0 + 2.0/3.0 + 1 + 1) / 4,
+			GlobalClustering: 6.0 / 8.0, Triangles: 2, Assortativity: -2.0 / 3.0, Diameter: 2}},
+		//ends of degree 1 only link to degree 2
+		{"path", path, NetworkStats{Nodes: 4, Edges: 3,
+			Density: 0.5, Components: 1, MinDegree: 1, MaxDegree: 2, AverageDegree: 1.5,
+			DegreeHistogram: []int{0, 2, 2}, Assortativity: -0.5, Diameter: 3}},
+	}
+	for _, test := range tests {
+		n := edgeListNet(t, test.edges)
+		for _, exact := range []bool{true, false} {
+			got := n.Stats(exact, 2)
+			want := test.want
+			want.DiameterExact = exact
+			checkStats(t, test.name, got, want)
+		}
+	}
+}
+
+func TestStatsComponents(t *testing.T) {
+	n := edgeListNet(t, append(cliqueEdges("a", "b", "c"), weightedEdge{"x", "y", 1.0}))
+	n.AddNode("alone")
+	got := n.Stats(true, 1)
+	if got.Components != 3 {
+		t.Errorf("found %d components, want 3", got.Components)
+	}
+	if got.MinDegree != 0 || got.Diameter != 1 || got.Triangles != 1 {
+		t.Errorf("min degree %d, diameter %d and %d triangles, want 0, 1 and 1",
+			got.MinDegree, got.Diameter, got.Triangles)
+	}
+}
+
+func checkStats(t *testing.T, name string, got, want NetworkStats) {
+	t.Helper()
+	close := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if got.Nodes != want.Nodes || got.Edges != want.Edges || got.Components != want.Components {
+		t.Errorf("%s: %d nodes, %d edges, %d components, want %d, %d, %d", name,
+			got.Nodes, got.Edges, got.Components, want.Nodes, want.Edges, want.Components)
+	}
+	if got.MinDegree != want.MinDegree || got.MaxDegree != want.MaxDegree ||
+		!slices.Equal(got.DegreeHistogram, want.DegreeHistogram) {
+		t.Errorf("%s: degrees %d-%d %v, want %d-%d %v", name, got.MinDegree, got.MaxDegree,
+			got.DegreeHistogram, want.MinDegree, want.MaxDegree, want.DegreeHistogram)
+	}
+	if got.Triangles != want.Triangles {
+		t.Errorf("%s: %d triangles, want %d", name, got.Triangles, want.Triangles)
+	}
+	if got.Diameter != want.Diameter || got.DiameterExact != want.DiameterExact {
+		t.Errorf("%s: diameter %d (exact %v), want %d (exact %v)", name,
+			got.Diameter, got.DiameterExact, want.Diameter, want.DiameterExact)
+	}
+	floats := []struct {
+		field     string
+		got, want float64
+	}{
+		{"density", got.Density, want.Density},
+		{"average degree", got.AverageDegree, want.AverageDegree},
+		{"average clustering", got.AverageClustering, want.AverageClustering},
+		{"global clustering", got.GlobalClustering, want.GlobalClustering},
+		{"assortativity", got.Assortativity, want.Assortativity},
+	}
+	for _, f := range floats {
+		if !close(f.got, f.want) {
+			t.Errorf("%s: %s %v, want %v", name, f.field, f.got, f.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package networks
+
+import (
+	"errors"
+	"math"
+)
+
+// ShortestPathDistances returns the hop count between every pair of nodes,
+// indexed by position in NodeSlice. Unreachable pairs are -1.
+func (n *SpatialNet) ShortestPathDistances(maxWorkers uint) [][]int {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+
+	dist := make([][]int, count)
+	parallelFor(count, maxWorkers, func(source int) {
+		row := make([]int, count)
+		for i := range row {
+			row[i] = -1
+		}
+		row[source] = 0
+		queue := []int{source}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, nbr := range csr.NodeNeighbors(current) {
+				if row[nbr] == -1 {
+					row[nbr] = row[current] + 1
+					queue = append(queue, nbr)
+				}
+			}
+		}
+		dist[source] = row
+	})
+	return dist
+}
+
+// StressLayout places nodes so their distance on screen matches their
+// shortest path distance, by stress majorization (Gansner et al. 2004).
+// With KamadaKawai set it instead minimizes the Kamada-Kawai energy
+// one node at a time with Newton-Raphson steps.
+// Both need all-pairs distances, so they are best kept under ~5k nodes.
+type StressLayout struct {
+	//Screen distance of a single hop
+	EdgeLength float32
+
+	//Use the Kamada-Kawai energy instead of stress majorization
+	KamadaKawai bool
+
+	//Converged once the relative change in stress between steps
+	//falls below this. For Kamada-Kawai, once the largest energy
+	//gradient of any node falls below this.
+	Tolerance float32
+
+	MaxWorkers uint
+
+	dist        [][]int
+	csr         *CSR
+	maxDist     int
+	stress      float64
+	change      float64
+	initialized bool
+	maxGradient float64
+	newX, newY  []float32
+}
+
+func NewStressLayout() *StressLayout {
+	return &StressLayout{EdgeLength: 30.0, Tolerance: 1e-4, MaxWorkers: 1}
+}
+
+func init() {
+	RegisterLayout("stress", func() Layout { return NewStressLayout() })
+	RegisterLayout("kamadakawai", func() Layout {
+		s := NewStressLayout()
+		s.KamadaKawai = true
+		return s
+	})
+}
+
+func (s *StressLayout) Init(n *SpatialNet) error {
+	if s.EdgeLength <= 0 {
+		return errors.New("stress layout needs a positive edge length")
+	}
+	s.dist = n.ShortestPathDistances(s.MaxWorkers)
+	s.csr = n.CSR()
+
+	//disconnected pairs are placed as if they were one
+	//hop further than the longest path in the network
+	s.maxDist = 0
+	for _, row := range s.dist {
+		for _, d := range row {
+			s.maxDist = max(s.maxDist, d)
+		}
+	}
+	s.newX = make([]float32, len(n.NodeSlice))
+	s.newY = make([]float32, len(n.NodeSlice))
+	s.stress = s.Stress(n)
+	s.change = math.Inf(1)
+	s.maxGradient = math.Inf(1)
+	s.initialized = true
+	return nil
+}
+
+// target distance and weight between nodes i and j
+func (s *StressLayout) target(i, j int) (float64, float64) {
+	d := s.dist[i][j]
+	if d < 0 {
+		d = s.maxDist + 1
+	}
+	targetDist := float64(d) * float64(s.EdgeLength)
+	return targetDist, 1.0 / (targetDist * targetDist)
+}
+
+// Stress returns sum over pairs of w_ij * (|x_i - x_j| - d_ij)^2
+// with w_ij = d_ij^-2, for the current positions
+func (s *StressLayout) Stress(n *SpatialNet) float64 {
+	var total float64
+	for i := range n.NodeSlice {
+		for j := i + 1; j < len(n.NodeSlice); j++ {
+			targetDist, weight := s.target(i, j)
+			a := &n.NodeSlice[i]
+			b := &n.NodeSlice[j]
+			dist := math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+			total += weight * (dist - targetDist) * (dist - targetDist)
+		}
+	}
+	return total
+}
+
+// FinalStress returns the stress after the last step taken
+func (s *StressLayout) FinalStress() float64 {
+	return s.stress
+}
+
+func (s *StressLayout) Step(n *SpatialNet) {
+	if !s.initialized || len(s.dist) != len(n.NodeSlice) || s.csr != n.CSR() {
+		s.Init(n)
+	}
+	if len(n.NodeSlice) < 2 {
+		s.change = 0
+		return
+	}
+
+	if s.KamadaKawai {
+		s.kamadaKawaiStep(n)
+	} else {
+		s.majorizationStep(n)
+	}
+
+	newStress := s.Stress(n)
+	if s.stress > 0 {
+		s.change = math.Abs(s.stress-newStress) / s.stress
+	} else {
+		s.change = 0
+	}
+	s.stress = newStress
+}
+
+// majorizationStep moves every node to the weighted average of where
+// each other node would like it to be (the Guttman transform)
+func (s *StressLayout) majorizationStep(n *SpatialNet) {
+	parallelFor(len(n.NodeSlice), s.MaxWorkers, func(i int) {
+		a := &n.NodeSlice[i]
+		var sumX, sumY, sumW float64
+		for j := range n.NodeSlice {
+			if i == j {
+				continue
+			}
+			b := &n.NodeSlice[j]
+			targetDist, weight := s.target(i, j)
+			dx := float64(a.X - b.X)
+			dy := float64(a.Y - b.Y)
+			dist := math.Hypot(dx, dy)
+			var scale float64
+			if dist > 0 {
+				scale = targetDist / dist
+			}
+			sumX += weight * (float64(b.X) + scale*dx)
+			sumY += weight * (float64(b.Y) + scale*dy)
+			sumW += weight
+		}
+		s.newX[i] = float32(sumX / sumW)
+		s.newY[i] = float32(sumY / sumW)
+	})
+
+	for i := range n.NodeSlice {
+		node := &n.NodeSlice[i]
+		oldX, oldY := node.X, node.Y
+		node.move(s.newX[i]-oldX, s.newY[i]-oldY)
+		node.Vx = node.X - oldX
+		node.Vy = node.Y - oldY
+	}
+}
+
+// kamadaKawaiGradient returns the first and second partial derivatives
+// of the Kamada-Kawai energy with respect to the position of node i
+func (s *StressLayout) kamadaKawaiGradient(n *SpatialNet, i int) (dx, dy, dxx, dyy, dxy float64) {
+	a := &n.NodeSlice[i]
+	for j := range n.NodeSlice {
+		if i == j {
+			continue
+		}
+		b := &n.NodeSlice[j]
+		targetDist, weight := s.target(i, j)
+		ox := float64(a.X - b.X)
+		oy := float64(a.Y - b.Y)
+		dist := max(math.Hypot(ox, oy), 1e-6)
+		cube := dist * dist * dist
+		dx += weight * (ox - targetDist*ox/dist)
+		dy += weight * (oy - targetDist*oy/dist)
+		dxx += weight * (1 - targetDist*oy*oy/cube)
+		dyy += weight * (1 - targetDist*ox*ox/cube)
+		dxy += weight * targetDist * ox * oy / cube
+	}
+	return
+}
+
+// kamadaKawaiStep moves the node with the largest energy gradient
+// with Newton-Raphson until it is locally settled
+func (s *StressLayout) kamadaKawaiStep(n *SpatialNet) {
+	gradients := make([]float64, len(n.NodeSlice))
+	parallelFor(len(n.NodeSlice), s.MaxWorkers, func(i int) {
+		//pinned nodes can't lower the energy
+		if n.NodeSlice[i].Pinned {
+			return
+		}
+		dx, dy, _, _, _ := s.kamadaKawaiGradient(n, i)
+		gradients[i] = math.Hypot(dx, dy)
+	})
+	worst := 0
+	for i := range gradients {
+		if gradients[i] > gradients[worst] {
+			worst = i
+		}
+	}
+	s.maxGradient = gradients[worst]
+
+	for i := range n.NodeSlice {
+		n.NodeSlice[i].Vx = 0.0
+		n.NodeSlice[i].Vy = 0.0
+	}
+
+	node := &n.NodeSlice[worst]
+	for range 100 {
+		dx, dy, dxx, dyy, dxy := s.kamadaKawaiGradient(n, worst)
+		det := dxx*dyy - dxy*dxy
+		if math.Abs(det) < 1e-12 {
+			break
+		}
+		moveX := (dxy*dy - dyy*dx) / det
+		moveY := (dxy*dx - dxx*dy) / det
+		oldX, oldY := node.X, node.Y
+		node.move(float32(moveX), float32(moveY))
+		node.Vx += node.X - oldX
+		node.Vy += node.Y - oldY
+		//constraints can stop the node short of the Newton step
+		if math.Hypot(float64(node.X-oldX), float64(node.Y-oldY)) < 1e-3 {
+			break
+		}
+	}
+}
+
+func (s *StressLayout) Converged() bool {
+	if s.KamadaKawai {
+		//a single Kamada-Kawai step only moves one node, so the
+		//change in stress says little about the whole layout
+		return s.maxGradient < float64(s.Tolerance)
+	}
+	return s.change < float64(s.Tolerance)
+}
+
+func (s *StressLayout) Parameters() map[string]float32 {
+	return map[string]float32{"edgeLength": s.EdgeLength,
+		"kamadaKawai": boolParameter(s.KamadaKawai),
+		"tolerance":   s.Tolerance,
+		"maxWorkers":  float32(s.MaxWorkers)}
+}
+
+func (s *StressLayout) SetParameter(name string, value float32) error {
+	switch name {
+	case "edgeLength":
+		s.EdgeLength = value
+	case "kamadaKawai":
+		s.KamadaKawai = value != 0
+	case "tolerance":
+		s.Tolerance = value
+	case "maxWorkers":
+		s.MaxWorkers = uint(max(value, 1))
+	default:
+		return unknownParameter(name)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package networks
+
+import (
+	"errors"
+	"slices"
+)
+
+// Subgraphs are new networks holding copies of the selected nodes, the
+// edges between them and their attributes. Nodes keep their positions
+// and their order in NodeSlice, so a subgraph can be shown without
+// being laid out again.
+
+// subgraph copies the nodes at the given indeces, which must be
+// sorted, along with the edges between them and all their attributes
+func (n *SpatialNet) subgraph(members []int) *SpatialNet {
+	s := n.componentNet(members)
+	for _, i := range members {
+		n.NodeAttributes.copyKey(s.NodeAttributes, n.NodeSlice[i].Name)
+	}
+	for source, targets := range s.Adjacencies {
+		for target := range targets {
+			n.EdgeAttributes.copyKey(s.EdgeAttributes, n.EdgeKey(source, target))
+		}
+	}
+	return s
+}
+
+// InducedSubgraph returns the network made of the named nodes
+// and every edge between two of them
+func (n *SpatialNet) InducedSubgraph(names []string) (*SpatialNet, error) {
+	members := make([]int, 0, len(names))
+	for _, name := range names {
+		i, exists := n.NodeIndeces[name]
+		if !exists {
+			return nil, errors.New("node " + name + " does not exist")
+		}
+		members = append(members, int(i))
+	}
+	slices.Sort(members)
+	return n.subgraph(slices.Compact(members)), nil
+}
+
+// EgoNetwork returns the subgraph induced by the nodes at most radius
+// hops from center. Edges of directed networks are followed both ways.
+func (n *SpatialNet) EgoNetwork(center string, radius int) (*SpatialNet, error) {
+	start, exists := n.NodeIndeces[center]
+	if !exists {
+		return nil, errors.New("node " + center + " does not exist")
+	}
+	if radius < 0 {
+		return nil, errors.New("ego network needs a radius >= 0")
+	}
+	csr := n.CSR()
+	dist := map[int]int{int(start): 0}
+	members := []int{int(start)}
+	for head := 0; head < len(members); head++ {
+		current := members[head]
+		if dist[current] == radius {
+			continue
+		}
+		for _, nbr := range csr.NodeNeighbors(current) {
+			if _, seen := dist[nbr]; !seen {
+				dist[nbr] = dist[current] + 1
+				members = append(members, nbr)
+			}
+		}
+	}
+	slices.Sort(members)
+	return n.subgraph(members), nil
+}
+
+// coreNumbers returns the core number of every node by index, the
+// largest k such that the node is in a subgraph where every node has
+// at least k neighbors (Batagelj and Zaversnik 2003). Self loops are
+// ignored and edges of directed networks are followed both ways.
+func (n *SpatialNet) coreNumbers() []int {
+	count := len(n.NodeSlice)
+	csr := n.CSR()
+	degree := make([]int, count)
+	maxDegree := 0
+	for i := range count {
+		degree[i] = csr.Degree(i)
+		if csr.HasEdge(i, i) {
+			degree[i]--
+		}
+		maxDegree = max(maxDegree, degree[i])
+	}
+
+	//bucket sort nodes by degree, pos[i] is the place of node i in order
+	//and start[d] the first place of a node with degree d
+	start := make([]int, maxDegree+2)
+	for _, d := range degree {
+		start[d+1]++
+	}
+	for d := 1; d < len(start); d++ {
+		start[d] += start[d-1]
+	}
+	order := make([]int, count)
+	pos := make([]int, count)
+	next := slices.Clone(start)
+	for i, d := range degree {
+		pos[i] = next[d]
+		order[pos[i]] = i
+		next[d]++
+	}
+
+	//peel nodes off in order of degree, moving each neighbor
+	//with a higher degree down into the bucket below
+	for _, i := range order {
+		for _, nbr := range csr.NodeNeighbors(i) {
+			if degree[nbr] <= degree[i] {
+				continue
+			}
+			d := degree[nbr]
+			first := order[start[d]]
+			if first != nbr {
+				order[pos[nbr]], order[start[d]] = first, nbr
+				pos[first], pos[nbr] = pos[nbr], start[d]
+			}
+			start[d]++
+			degree[nbr]--
+		}
+	}
+	return degree
+}
+
+// CoreNumbers returns the core number of every node, see KCore
+func (n *SpatialNet) CoreNumbers() map[string]int {
+	cores := make(map[string]int, len(n.NodeSlice))
+	for i, core := range n.coreNumbers() {
+		cores[n.NodeSlice[i].Name] = core
+	}
+	return cores
+}
+
+// KCore returns the k-core, the largest subgraph where every node has
+// at least k neighbors. The core number of every node in it is stored
+// in the int node attribute "core".
+func (n *SpatialNet) KCore(k int) *SpatialNet {
+	cores := n.coreNumbers()
+	var members []int
+	for i, core := range cores {
+		if core >= k {
+			members = append(members, i)
+		}
+	}
+	s := n.subgraph(members)
+	for _, i := range members {
+		s.NodeAttributes.SetInt(n.NodeSlice[i].Name, "core", cores[i])
+	}
+	return s
+}
+
+// LargestComponent returns the largest connected component,
+// the earliest in NodeSlice when several have the same size
+func (n *SpatialNet) LargestComponent() *SpatialNet {
+	components := n.ConnectedComponents()
+	if len(components) == 0 {
+		return n.subgraph(nil)
+	}
+	return n.subgraph(components[0])
+}
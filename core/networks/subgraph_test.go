@@ -0,0 +1,69 @@
+package networks
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCoreNumbers(t *testing.T) {
+	//a clique on a, b, c, d with the tail d-e-f
+	cliqueWithTail := append(cliqueEdges("a", "b", "c", "d"),
+		weightedEdge{"d", "e", 1.0}, weightedEdge{"e", "f", 1.0})
+
+	tests := []struct {
+		name  string
+		edges []weightedEdge
+		want  map[string]int
+	}{
+		{"clique with tail", cliqueWithTail,
+			map[string]int{"a": 3, "b": 3, "c": 3, "d": 3, "e": 1, "f": 1}},
+		{"self loop ignored", append(slices.Clone(cliqueWithTail), weightedEdge{"f", "f", 1.0}),
+			map[string]int{"a": 3, "b": 3, "c": 3, "d": 3, "e": 1, "f": 1}},
+		//the triangle x, y, z hangs off the clique by the edge x-a
+		{"clique with triangle", append(cliqueEdges("a", "b", "c", "d", "e"),
+			append(cliqueEdges("x", "y", "z"), weightedEdge{"x", "a", 1.0})...),
+			map[string]int{"a": 4, "b": 4, "c": 4, "d": 4, "e": 4, "x": 2, "y": 2, "z": 2}},
+		{"path", []weightedEdge{{"a", "b", 1.0}, {"b", "c", 1.0}},
+			map[string]int{"a": 1, "b": 1, "c": 1}},
+		{"cycle", []weightedEdge{{"a", "b", 1.0}, {"b", "c", 1.0}, {"c", "d", 1.0}, {"d", "a", 1.0}},
+			map[string]int{"a": 2, "b": 2, "c": 2, "d": 2}},
+	}
+	for _, test := range tests {
+		n := edgeListNet(t, test.edges)
+		got := n.CoreNumbers()
+		for node, want := range test.want {
+			if got[node] != want {
+				t.Errorf("%s: core number of %s is %d, want %d", test.name, node, got[node], want)
+			}
+		}
+	}
+}
+
+func TestCoreNumbersIsolatedNode(t *testing.T) {
+	n := edgeListNet(t, cliqueEdges("a", "b", "c"))
+	n.AddNode("alone")
+	if core := n.CoreNumbers()["alone"]; core != 0 {
+		t.Errorf("core number of an isolated node is %d, want 0", core)
+	}
+}
+
+func TestKCore(t *testing.T) {
+	edges := append(cliqueEdges("a", "b", "c", "d"),
+		weightedEdge{"d", "e", 1.0}, weightedEdge{"e", "f", 1.0})
+	n := edgeListNet(t, edges)
+	core := n.KCore(2)
+	var names []string
+	for _, node := range core.NodeSlice {
+		names = append(names, node.Name)
+		if value, _ := core.NodeAttributes.Int(node.Name, "core"); value != 3 {
+			t.Errorf("stored core of %s is %d, want 3", node.Name, value)
+		}
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"a", "b", "c", "d"}) {
+		t.Errorf("2-core holds %v, want the clique", names)
+	}
+	if core.ContainsEdge("d", "e") {
+		t.Error("2-core kept the edge to the tail")
+	}
+}
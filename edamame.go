@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/KirtusLeyba/edamame/app"
+	ednet "github.com/KirtusLeyba/edamame/core/networks"
 	"flag"
 	"os"
+	"strings"
 )
 
 func isSet(name string) bool {
@@ -27,12 +29,23 @@ func main() {
 	flag.IntVar(&opt.MaxWorkers, "maxWorkers", 1, "Number of go routines to use to generate layout")
 	flag.IntVar(&opt.MaxIters, "maxIters", 1, "Number of iterations in the layout algorithm")
 	flag.Float64Var(&opt.Repulsion, "repulsion", 80, "Repulsive force")
+	flag.StringVar(&opt.Layout, "layout", "spring", "Layout algorithm, one of: "+strings.Join(ednet.LayoutNames(), ", "))
+	flag.BoolVar(&opt.Directed, "directed", false, "Treat the edges in edgeFilePath as directed, from nodeA to nodeB")
+	flag.Float64Var(&opt.Tolerance, "tolerance", 0.0, "Stop the layout once no node moves further than this in a step, 0 runs all maxIters")
+	flag.Float64Var(&opt.EnergyTolerance, "energyTolerance", 0, "Also require the total kinetic energy of a step to be under this, 0 ignores energy")
+	flag.IntVar(&opt.Patience, "patience", 10, "Number of consecutive steps under tolerance before stopping")
+	flag.StringVar(&opt.LayoutParams, "layoutParams", "", "Comma separated layout parameters, e.g. theta=0.5,friction=0.2")
+	flag.Int64Var(&opt.Seed, "seed", 0, "Seed for initial positions and layouts, 0 picks a random seed that is written to the log")
+	flag.StringVar(&opt.Communities, "communities", "", "Colour nodes by community, detected with louvain, leiden or labelpropagation")
+	flag.BoolVar(&opt.PackComponents, "packComponents", false, "Lay out each connected component on its own and pack them into a rectangle")
+	flag.StringVar(&opt.Stats, "stats", "", "Print a summary of the network before the layout, as a table or json")
+	flag.BoolVar(&opt.RemoveOverlaps, "removeOverlaps", false, "Push apart nodes that overlap in the image once the layout is done")
 	flag.Parse()
 
 	if !opt.Headless {
 		var defaultWidth int32 = 800
 		var defaultHeight int32 = 600
-		app.Execute(defaultWidth, defaultHeight)
+		app.Execute(defaultWidth, defaultHeight, opt.Seed)
 	} else {
 		if !isSet("nodeFilePath") || !isSet("edgeFilePath") || !isSet("outputFilePath") {
 			flag.PrintDefaults()
@@ -2,6 +2,6 @@ module edamame/performanceTests
 
 go 1.25.1
 
-replace edamame/core => ../core
+replace github.com/KirtusLeyba/edamame => ../
 
-require edamame/core v0.0.0-00010101000000-000000000000 // indirect
+require github.com/KirtusLeyba/edamame v0.0.0-00010101000000-000000000000
@@ -1,9 +1,10 @@
 package main
 
 import (
-	ednet "edamame/core/networks"
 	"fmt"
+	ednet "github.com/KirtusLeyba/edamame/core/networks"
 	"math"
+	"math/rand"
 	"time"
 )
 
@@ -20,10 +21,10 @@ func main() {
 	// fmt.Printf("Simple serial method:\n")
 	// for i := range trials{
 	// 	numNodes := 100*int(math.Pow(2, float64(i)))
-	// 	n := ednet.NewRandomSpatialNet(numNodes, 0.1)
+	// 	n := ednet.NewRandomSpatialNet(numNodes, 0.1, rand.New(rand.NewSource(1)))
 	// 	start := time.Now()
 	// 	for range 100 {
-	// 		n.SpringUpdate(0.1, 0.1, 1.0, 1.0, 0.001)
+	// 		n.SpringUpdate(0.1, 0.1, 1.0, 1.0, 0.001, ednet.WeightNone)
 	// 	}
 	// 	elapsed := time.Since(start)
 	// 	fmt.Printf("Nodes: %v, Elapsed time: %v seconds\n", numNodes, elapsed.Seconds())
@@ -33,10 +34,10 @@ func main() {
 	fmt.Printf("Using go routines:\n")
 	for i := range trials {
 		numNodes := 100 * int(math.Pow(2, float64(i)))
-		n := ednet.NewRandomSpatialNet(numNodes, 0.1)
+		n := ednet.NewRandomSpatialNet(numNodes, 0.1, rand.New(rand.NewSource(1)))
 		start := time.Now()
 		for range 100 {
-			n.SpringUpdateParallel(0.1, 0.1, 1.0, 1.0, 0.001)
+			n.SpringUpdateParallel(0.1, 0.1, 1.0, 1.0, 0.001, 8, ednet.WeightNone)
 		}
 		elapsed := time.Since(start)
 		fmt.Printf("Nodes: %v, Elapsed time: %v seconds\n", numNodes, elapsed.Seconds())
@@ -46,25 +47,38 @@ func main() {
 	// fmt.Printf("Using serial spatial hashing\n")
 	// for i := range trials {
 	// 	numNodes := 100 * int(math.Pow(2, float64(i)))
-	// 	n := ednet.NewRandomSpatialNet(numNodes, 0.1)
+	// 	n := ednet.NewRandomSpatialNet(numNodes, 0.1, rand.New(rand.NewSource(1)))
 	// 	start := time.Now()
 	// 	for range 100 {
-	// 		n.SpringUpdateHashing(0.1, 0.1, 1.0, 1.0, 0.001)
+	// 		n.SpringUpdateHashing(0.1, 0.1, 1.0, 1.0, 0.001, ednet.WeightNone)
 	// 	}
 	// 	elapsed := time.Since(start)
 	// 	fmt.Printf("Nodes: %v, Elapsed time: %v seconds\n", numNodes, elapsed.Seconds())
 	// }
 	// sep()
 
+	fmt.Printf("Using Barnes-Hut\n")
+	for i := range trials {
+		numNodes := 100 * int(math.Pow(2, float64(i)))
+		n := ednet.NewRandomSpatialNet(numNodes, 0.1, rand.New(rand.NewSource(1)))
+		start := time.Now()
+		for range 100 {
+			n.SpringUpdateBarnesHut(0.1, 0.1, 1.0, 1.0, 0.001, 0.8, 8, ednet.WeightNone)
+		}
+		elapsed := time.Since(start)
+		fmt.Printf("Nodes: %v, Elapsed time: %v seconds\n", numNodes, elapsed.Seconds())
+	}
+	sep()
+
 	fmt.Printf("Using parallel spatial hashing\n")
 	for i := range trials {
 		numNodes := 100 * int(math.Pow(2, float64(i)))
-		n := ednet.NewRandomSpatialNet(numNodes, 0.1)
+		n := ednet.NewRandomSpatialNet(numNodes, 0.1, rand.New(rand.NewSource(1)))
 		var binSize float32 = 1000.0
 		n.ResetSpatialHashing(binSize)
 		start := time.Now()
 		for range 100 {
-			n.SpringUpdateHashingParallel(0.1, 0.1, 1.0, 1.0, 0.001, binSize)
+			n.SpringUpdateHashingParallel(0.1, 0.1, 1.0, 1.0, 0.001, binSize, ednet.WeightNone)
 		}
 		elapsed := time.Since(start)
 		fmt.Printf("Nodes: %v, Elapsed time: %v seconds\n", numNodes, elapsed.Seconds())